@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -17,11 +18,13 @@ import (
 	"github.com/kstsm/wb-sales-tracker/internal/service"
 	"github.com/kstsm/wb-sales-tracker/pkg/logger"
 	"github.com/kstsm/wb-sales-tracker/pkg/validator"
+	"github.com/kstsm/wb-sales-tracker/pkg/wbapi"
 )
 
 const (
 	httpServerShutdownTimeout = 5
 	readHeaderTimeout         = 5
+	wbAPITimeout              = 60
 )
 
 func Run() error {
@@ -37,8 +40,19 @@ func Run() error {
 	validate := validator.NewValidator()
 
 	repo := repository.NewRepository(conn, log)
-	svc := service.NewService(repo, log)
-	router := handler.NewHandler(svc, log, validate)
+	svc := service.NewService(repo, log, validate, cfg.OneC, cfg.OFX, cfg.Duplicate)
+	wbClient := wbapi.NewClient(cfg.WBSync.BaseURL, cfg.WBSync.Token, wbAPITimeout*time.Second)
+	wbSync := service.NewWBSyncer(repo, log, validate, wbClient, cfg.WBSync)
+	jobs := service.NewJobRunner(repo, svc, log, cfg.Jobs)
+	router := handler.NewHandler(svc, wbSync, jobs, log, validate, cfg.Analytics)
+
+	var workers sync.WaitGroup
+	workers.Go(func() { wbSync.Run(ctx) })
+	workers.Go(func() { jobs.Run(ctx) })
+	defer func() {
+		stop()
+		workers.Wait()
+	}()
 
 	srv := &http.Server{
 		Addr:              fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
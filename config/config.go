@@ -2,14 +2,23 @@ package config
 
 import (
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gookit/slog"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   Server
-	Postgres Postgres
+	Server    Server
+	Postgres  Postgres
+	Analytics Analytics
+	OneC      OneC
+	OFX       OFX
+	WBSync    WBSync
+	Duplicate Duplicate
+	Jobs      Jobs
 }
 
 type Server struct {
@@ -26,8 +35,70 @@ type Postgres struct {
 	Ssl      string
 }
 
+type Analytics struct {
+	Location *time.Location
+}
+
+// OneC holds the bank details written to 1CClientBankExchange exports. The
+// counterparty fields are placeholders, since items do not record who paid
+// or was paid; CounterpartyName and Purpose may use {category}.
+type OneC struct {
+	Account             string
+	CompanyName         string
+	CompanyINN          string
+	BankBIC             string
+	CounterpartyName    string
+	CounterpartyINN     string
+	CounterpartyAccount string
+	CounterpartyBIC     string
+	Purpose             string
+}
+
+// OFX holds the account that OFX exports are attributed to. Importers map
+// statements to an account by these IDs, so they must stay the same between
+// exports.
+type OFX struct {
+	AccountID string
+	BankID    string
+}
+
+// Duplicate configures the check for suspected duplicates on ingestion:
+// items of the same type, amount and category dated within Window of each
+// other. A zero Window turns the check off.
+type Duplicate struct {
+	Window time.Duration
+}
+
+// Jobs configures the pool that runs background imports. A job is tried
+// at most MaxAttempts times when restarts keep interrupting it.
+type Jobs struct {
+	Workers      int
+	PollInterval time.Duration
+	MaxAttempts  int
+}
+
+// WBSync configures the pull of sales or orders from the Wildberries
+// statistics API. Sync is off while Token is empty.
+type WBSync struct {
+	Token        string
+	BaseURL      string
+	PollInterval time.Duration
+	StartDate    time.Time
+	Sources      []string
+}
+
 func GetConfig() Config {
 	viper.SetConfigFile(".env")
+	viper.SetDefault("ONEC_COUNTERPARTY_NAME", "Контрагент ({category})")
+	viper.SetDefault("ONEC_PURPOSE", "{category}")
+	viper.SetDefault("OFX_ACCOUNT_ID", "wb-sales-tracker")
+	viper.SetDefault("OFX_BANK_ID", "000000000")
+	viper.SetDefault("WB_SYNC_INTERVAL", "30m")
+	viper.SetDefault("WB_SYNC_SOURCES", "sales")
+	viper.SetDefault("DUPLICATE_WINDOW", "24h")
+	viper.SetDefault("JOB_WORKERS", 2)
+	viper.SetDefault("JOB_POLL_INTERVAL", "1s")
+	viper.SetDefault("JOB_MAX_ATTEMPTS", 3)
 
 	err := viper.ReadInConfig()
 	if err != nil {
@@ -35,6 +106,66 @@ func GetConfig() Config {
 		os.Exit(1)
 	}
 
+	location, err := time.LoadLocation(viper.GetString("ANALYTICS_TIMEZONE"))
+	if err != nil {
+		slog.Fatal("Invalid ANALYTICS_TIMEZONE", "error", err)
+		os.Exit(1)
+	}
+
+	pollInterval, err := time.ParseDuration(viper.GetString("WB_SYNC_INTERVAL"))
+	if err != nil || pollInterval <= 0 {
+		slog.Fatal("Invalid WB_SYNC_INTERVAL", "error", err)
+		os.Exit(1)
+	}
+
+	var startDate time.Time
+	if startDateStr := viper.GetString("WB_SYNC_START_DATE"); startDateStr != "" {
+		startDate, err = time.ParseInLocation(time.DateOnly, startDateStr, location)
+		if err != nil {
+			slog.Fatal("Invalid WB_SYNC_START_DATE", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var sources []string
+	for source := range strings.SplitSeq(viper.GetString("WB_SYNC_SOURCES"), ",") {
+		if source = strings.TrimSpace(source); source != "" {
+			sources = append(sources, source)
+		}
+	}
+
+	// Orders are booked as income before they are bought out, so together
+	// with sales the same revenue would be counted twice.
+	if slices.Contains(sources, "sales") && slices.Contains(sources, "orders") {
+		slog.Fatal("Invalid WB_SYNC_SOURCES: sales and orders both book the same revenue, choose one",
+			"value", viper.GetString("WB_SYNC_SOURCES"))
+		os.Exit(1)
+	}
+
+	duplicateWindow, err := time.ParseDuration(viper.GetString("DUPLICATE_WINDOW"))
+	if err != nil || duplicateWindow < 0 {
+		slog.Fatal("Invalid DUPLICATE_WINDOW", "error", err)
+		os.Exit(1)
+	}
+
+	jobPollInterval, err := time.ParseDuration(viper.GetString("JOB_POLL_INTERVAL"))
+	if err != nil || jobPollInterval <= 0 {
+		slog.Fatal("Invalid JOB_POLL_INTERVAL", "error", err)
+		os.Exit(1)
+	}
+
+	jobWorkers := viper.GetInt("JOB_WORKERS")
+	if jobWorkers < 1 {
+		slog.Fatal("Invalid JOB_WORKERS", "value", viper.GetString("JOB_WORKERS"))
+		os.Exit(1)
+	}
+
+	jobMaxAttempts := viper.GetInt("JOB_MAX_ATTEMPTS")
+	if jobMaxAttempts < 1 {
+		slog.Fatal("Invalid JOB_MAX_ATTEMPTS", "value", viper.GetString("JOB_MAX_ATTEMPTS"))
+		os.Exit(1)
+	}
+
 	return Config{
 		Server: Server{
 			Host: viper.GetString("SRV_HOST"),
@@ -48,5 +179,38 @@ func GetConfig() Config {
 			DBName:   viper.GetString("POSTGRES_DB"),
 			Ssl:      viper.GetString("POSTGRES_SSL"),
 		},
+		Analytics: Analytics{
+			Location: location,
+		},
+		OneC: OneC{
+			Account:             viper.GetString("ONEC_ACCOUNT"),
+			CompanyName:         viper.GetString("ONEC_COMPANY_NAME"),
+			CompanyINN:          viper.GetString("ONEC_COMPANY_INN"),
+			BankBIC:             viper.GetString("ONEC_BANK_BIC"),
+			CounterpartyName:    viper.GetString("ONEC_COUNTERPARTY_NAME"),
+			CounterpartyINN:     viper.GetString("ONEC_COUNTERPARTY_INN"),
+			CounterpartyAccount: viper.GetString("ONEC_COUNTERPARTY_ACCOUNT"),
+			CounterpartyBIC:     viper.GetString("ONEC_COUNTERPARTY_BIC"),
+			Purpose:             viper.GetString("ONEC_PURPOSE"),
+		},
+		OFX: OFX{
+			AccountID: viper.GetString("OFX_ACCOUNT_ID"),
+			BankID:    viper.GetString("OFX_BANK_ID"),
+		},
+		WBSync: WBSync{
+			Token:        viper.GetString("WB_API_TOKEN"),
+			BaseURL:      viper.GetString("WB_STATISTICS_URL"),
+			PollInterval: pollInterval,
+			StartDate:    startDate,
+			Sources:      sources,
+		},
+		Duplicate: Duplicate{
+			Window: duplicateWindow,
+		},
+		Jobs: Jobs{
+			Workers:      jobWorkers,
+			PollInterval: jobPollInterval,
+			MaxAttempts:  jobMaxAttempts,
+		},
 	}
 }
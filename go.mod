@@ -9,6 +9,7 @@ require (
 	github.com/gookit/slog v0.6.0
 	github.com/jackc/pgx/v5 v5.7.6
 	github.com/spf13/viper v1.21.0
+	golang.org/x/text v0.29.0
 )
 
 require (
@@ -39,5 +40,4 @@ require (
 	golang.org/x/sync v0.17.0 // indirect
 	golang.org/x/sys v0.36.0 // indirect
 	golang.org/x/term v0.35.0 // indirect
-	golang.org/x/text v0.29.0 // indirect
 )
@@ -3,6 +3,13 @@ package apperrors
 import "errors"
 
 var (
-	ErrItemNotFound = errors.New("item not found")
-	ErrEmptyDate    = errors.New("empty date string")
+	ErrItemNotFound  = errors.New("item not found")
+	ErrEmptyDate     = errors.New("empty date string")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrTooManyGroups = errors.New("too many groups to fill, narrow the period or use a larger group_by")
+	ErrInvalidImport = errors.New("invalid import file")
+	ErrNotConfigured = errors.New("export is not configured")
+	ErrDuplicate     = errors.New("suspected duplicate")
+	ErrJobNotFound   = errors.New("job not found")
+	ErrJobFinished   = errors.New("job has already finished")
 )
@@ -3,17 +3,59 @@ package converter
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/pkg/groupby"
 )
 
+// ParseGroupInterval converts a custom group_by interval such as "3d" or "2w"
+// into a Postgres interval literal.
+func ParseGroupInterval(groupBy string) (string, bool) {
+	n, unit, ok := groupby.SplitInterval(groupBy)
+	if !ok {
+		return "", false
+	}
+
+	switch unit {
+	case 'h':
+		return fmt.Sprintf("%d hours", n), true
+	case 'd':
+		return fmt.Sprintf("%d days", n), true
+	default:
+		return fmt.Sprintf("%d weeks", n), true
+	}
+}
+
+// GroupIntervalDuration returns the length of a custom group_by interval.
+func GroupIntervalDuration(groupBy string) (time.Duration, bool) {
+	const hoursPerDay, daysPerWeek = 24, 7
+
+	n, unit, ok := groupby.SplitInterval(groupBy)
+	if !ok {
+		return 0, false
+	}
+
+	switch unit {
+	case 'h':
+		return time.Duration(n) * time.Hour, true
+	case 'd':
+		return time.Duration(n*hoursPerDay) * time.Hour, true
+	default:
+		return time.Duration(n*hoursPerDay*daysPerWeek) * time.Hour, true
+	}
+}
+
 func FormatGroupKey(groupKey any, groupBy string) string {
 	switch v := groupKey.(type) {
 	case time.Time:
-		if groupBy == "week" {
-			year, week := v.UTC().ISOWeek()
-			return fmt.Sprintf("%d-W%02d", year, week)
-		}
-		return v.UTC().Format("2006-01-02")
+		return formatTimeGroupKey(v.UTC(), groupBy)
+	case int32:
+		return formatNumericGroupKey(int(v), groupBy)
+	case int64:
+		return formatNumericGroupKey(int(v), groupBy)
 	case string:
 		return v
 	default:
@@ -21,6 +63,38 @@ func FormatGroupKey(groupKey any, groupBy string) string {
 	}
 }
 
+func formatTimeGroupKey(t time.Time, groupBy string) string {
+	switch groupBy {
+	case "week":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case "month":
+		return t.Format("2006-01")
+	case "quarter":
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case "year":
+		return t.Format("2006")
+	}
+
+	if _, ok := ParseGroupInterval(groupBy); ok && strings.HasSuffix(groupBy, "h") {
+		return t.Format("2006-01-02 15:04")
+	}
+
+	return t.Format("2006-01-02")
+}
+
+func formatNumericGroupKey(n int, groupBy string) string {
+	switch groupBy {
+	case "weekday":
+		const daysPerWeek = 7
+		return time.Weekday(n % daysPerWeek).String()
+	case "hour":
+		return fmt.Sprintf("%02d:00", n)
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
 func ToFloatPtr(n sql.NullFloat64) *float64 {
 	if !n.Valid {
 		return nil
@@ -36,3 +110,69 @@ func ToFloatPtrIf(n sql.NullFloat64, condition bool) *float64 {
 	v := n.Float64
 	return &v
 }
+
+// AnalyticsTotalGroup labels the totals row of an analytics export.
+const AnalyticsTotalGroup = "total"
+
+// AnalyticsToExportRows flattens an analytics response into one row per
+// grouped bucket followed by a totals row.
+func AnalyticsToExportRows(resp *dto.AnalyticsResponse) []dto.AnalyticsExportRow {
+	rows := make([]dto.AnalyticsExportRow, 0, len(resp.Grouped)+1)
+
+	for _, g := range resp.Grouped {
+		row := dto.AnalyticsExportRow{
+			Group:         g.Group,
+			Sum:           g.Sum,
+			Avg:           g.Avg,
+			Count:         g.Count,
+			Median:        g.Median,
+			Percentile90:  g.Percentile90,
+			Income:        g.Income,
+			Expense:       g.Expense,
+			Net:           g.Net,
+			IncomeCount:   g.IncomeCount,
+			ExpenseCount:  g.ExpenseCount,
+			PreviousGroup: g.PreviousGroup,
+		}
+		setExportComparison(&row, g.Comparison)
+		rows = append(rows, row)
+	}
+
+	sum, income, expense, net := resp.Sum, resp.Income, resp.Expense, resp.Net
+	total := dto.AnalyticsExportRow{
+		Group:        AnalyticsTotalGroup,
+		Sum:          &sum,
+		Avg:          resp.Avg,
+		Count:        resp.Count,
+		Median:       resp.Median,
+		Percentile90: resp.Percentile90,
+		Income:       &income,
+		Expense:      &expense,
+		Net:          &net,
+		IncomeCount:  resp.IncomeCount,
+		ExpenseCount: resp.ExpenseCount,
+	}
+	if resp.Comparison != nil {
+		total.PreviousGroup = resp.Comparison.From + "/" + resp.Comparison.To
+		setExportComparison(&total, resp.Comparison.Metrics)
+	}
+
+	return append(rows, total)
+}
+
+func setExportComparison(row *dto.AnalyticsExportRow, metrics map[string]dto.MetricDelta) {
+	if metrics == nil {
+		return
+	}
+
+	row.SumPrevious, row.SumChange = metrics["sum"].Previous, metrics["sum"].Percent
+	row.CountChange = metrics["count"].Percent
+	row.IncomePrev, row.IncomeChange = metrics["income"].Previous, metrics["income"].Percent
+	row.ExpensePrev, row.ExpenseChange = metrics["expense"].Previous, metrics["expense"].Percent
+	row.NetPrevious, row.NetChange = metrics["net"].Previous, metrics["net"].Percent
+
+	if prev := metrics["count"].Previous; prev != nil {
+		count := int(*prev)
+		row.CountPrevious = &count
+	}
+}
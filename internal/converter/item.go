@@ -1,15 +1,25 @@
 package converter
 
 import (
+	"encoding/base64"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
 	"github.com/kstsm/wb-sales-tracker/internal/dto"
 	"github.com/kstsm/wb-sales-tracker/internal/models"
 )
 
 const (
-	kopeksPerRuble = 100
+	kopeksPerRuble  = 100
+	cursorSeparator = "|"
+
+	// MaxAmount is the largest amount in kopeks the INT amount column holds.
+	MaxAmount = math.MaxInt32
 )
 
 func FormatRublesAmount(amount int) string {
@@ -23,15 +33,63 @@ func FormatRublesAmount(amount int) string {
 	return fmt.Sprintf("%d.%02d", rubles, kopeks)
 }
 
+// RublesFromKopeks converts an amount in kopeks to rubles.
+func RublesFromKopeks(amount int) float64 {
+	return float64(amount) / kopeksPerRuble
+}
+
+// ParseRublesAmount is the inverse of FormatRublesAmount: it converts a ruble
+// amount such as "15000.5" or "15000,50" into kopeks.
+func ParseRublesAmount(s string) (int, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+
+	rublesStr, kopeksStr, hasKopeks := strings.Cut(s, ".")
+	if !isDigits(rublesStr) || (hasKopeks && (!isDigits(kopeksStr) || len(kopeksStr) > 2)) {
+		return 0, fmt.Errorf("invalid amount '%s'", s)
+	}
+
+	kopeks := 0
+	if hasKopeks {
+		if len(kopeksStr) == 1 {
+			kopeksStr += "0"
+		}
+		kopeks, _ = strconv.Atoi(kopeksStr)
+	}
+
+	// Atoi fails on values past int, and the bound is checked before the
+	// multiplication so it cannot overflow either.
+	rubles, err := strconv.Atoi(rublesStr)
+	if err != nil || rubles > (MaxAmount-kopeks)/kopeksPerRuble {
+		return 0, fmt.Errorf("amount '%s' exceeds the maximum of %s", s, FormatRublesAmount(MaxAmount))
+	}
+
+	return rubles*kopeksPerRuble + kopeks, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
 func ItemToResponse(item *models.Item) dto.ItemResponse {
 	return dto.ItemResponse{
-		ID:        item.ID.String(),
-		Type:      item.Type,
-		Amount:    FormatRublesAmount(item.Amount),
-		Date:      item.Date.UTC().Format(time.RFC3339),
-		Category:  item.Category,
-		CreatedAt: item.CreatedAt.UTC().Format(time.RFC3339),
-		UpdatedAt: item.UpdatedAt.UTC().Format(time.RFC3339),
+		ID:         item.ID.String(),
+		Type:       item.Type,
+		Amount:     FormatRublesAmount(item.Amount),
+		Date:       item.Date.UTC().Format(time.RFC3339),
+		Category:   item.Category,
+		Source:     item.Source,
+		ExternalID: item.ExternalID,
+		CreatedAt:  item.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:  item.UpdatedAt.UTC().Format(time.RFC3339),
 	}
 }
 
@@ -43,3 +101,46 @@ func ItemsToResponse(items []*models.Item) []dto.ItemResponse {
 
 	return res
 }
+
+func EncodeItemsCursor(item *models.Item) string {
+	raw := item.Date.UTC().Format(time.RFC3339Nano) + cursorSeparator + item.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeItemsCursor(s string) (*dto.ItemsCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, apperrors.ErrInvalidCursor
+	}
+
+	dateStr, idStr, ok := strings.Cut(string(data), cursorSeparator)
+	if !ok {
+		return nil, apperrors.ErrInvalidCursor
+	}
+
+	date, err := time.Parse(time.RFC3339Nano, dateStr)
+	if err != nil {
+		return nil, apperrors.ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, apperrors.ErrInvalidCursor
+	}
+
+	return &dto.ItemsCursor{Date: date, ID: id}, nil
+}
+
+// ItemsNextCursor returns the cursor of the page following items, or nil when
+// the page is not full or the list is not sorted by date.
+func ItemsNextCursor(items []*models.Item, req dto.GetItemsRequest) *string {
+	if req.Limit == nil || len(items) == 0 || len(items) < *req.Limit {
+		return nil
+	}
+	if req.SortBy != nil && *req.SortBy != "date" {
+		return nil
+	}
+
+	cursor := EncodeItemsCursor(items[len(items)-1])
+	return &cursor
+}
@@ -0,0 +1,68 @@
+package converter
+
+import (
+	"time"
+
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+)
+
+const percent = 100
+
+// Shares of the progress given to reading the file and processing its rows.
+// Writing takes the rest but one point, which the job gets on success.
+const (
+	jobReadingShare    = 40
+	jobProcessingShare = 50
+)
+
+func JobToResponse(job *models.Job) dto.JobResponse {
+	return dto.JobResponse{
+		ID:            job.ID.String(),
+		Type:          job.Type,
+		Status:        job.Status,
+		Progress:      jobProgress(job),
+		Phase:         job.Phase,
+		ReadBytes:     job.ReadBytes,
+		TotalBytes:    job.TotalBytes,
+		ProcessedRows: job.ProcessedRows,
+		TotalRows:     job.TotalRows,
+		Attempts:      job.Attempts,
+		Error:         job.Error,
+		Result:        job.Result,
+		CreatedAt:     job.CreatedAt.UTC().Format(time.RFC3339),
+		StartedAt:     formatOptionalTime(job.StartedAt),
+		FinishedAt:    formatOptionalTime(job.FinishedAt),
+		UpdatedAt:     job.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// jobProgress stays below 100 until the job succeeds. Reading is measured
+// in bytes and the later phases in rows; the write of the rows is a single
+// transaction, so it counts only once it is done.
+func jobProgress(job *models.Job) int {
+	if job.Status == models.JobSucceeded {
+		return percent
+	}
+
+	switch job.Phase {
+	case models.JobPhaseProcessing:
+		return jobReadingShare + share(job.ProcessedRows, job.TotalRows, jobProcessingShare)
+	case models.JobPhaseWriting:
+		writingShare := percent - 1 - jobReadingShare - jobProcessingShare
+		return jobReadingShare + jobProcessingShare + share(job.ProcessedRows, job.TotalRows, writingShare)
+	case models.JobPhaseReading:
+		return share(int(job.ReadBytes), int(job.TotalBytes), jobReadingShare)
+	default:
+		return 0
+	}
+}
+
+// share returns done of total scaled to width, or 0 for an empty total.
+func share(done, total, width int) int {
+	if total <= 0 {
+		return 0
+	}
+
+	return min(done, total) * width / total
+}
@@ -0,0 +1,29 @@
+package converter
+
+import (
+	"time"
+
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+)
+
+func SyncStateToResponse(state *models.SyncState) dto.WBSyncSourceStatus {
+	return dto.WBSyncSourceStatus{
+		Source:            state.Source,
+		LastChangeAt:      formatOptionalTime(&state.LastChangeAt),
+		LastSyncAt:        formatOptionalTime(state.LastSyncAt),
+		LastSuccessAt:     formatOptionalTime(state.LastSuccessAt),
+		LastError:         state.LastError,
+		LastCount:         state.LastCount,
+		LastSkipped:       state.LastSkipped,
+		LastSkippedReason: state.LastSkippedReason,
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
@@ -2,40 +2,150 @@ package dto
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type CreateItemRequest struct {
-	Type     string `json:"type"     validate:"required,item_type"`
-	Amount   int    `json:"amount"   validate:"required,gt=0"`
-	Date     string `json:"date"     validate:"required,rfc3339"`
-	Category string `json:"category" validate:"required,min=3"`
+	Type           string `json:"type"            validate:"required,item_type"`
+	Amount         int    `json:"amount"          validate:"required,gt=0,lte=2147483647"`
+	Date           string `json:"date"            validate:"required,rfc3339"`
+	Category       string `json:"category"        validate:"required,min=3,max=32"`
+	Source         string `json:"source"          validate:"required_with=ExternalID,max=32"`
+	ExternalID     string `json:"external_id"     validate:"required_with=Source,max=128"`
+	AllowDuplicate bool   `json:"allow_duplicate"`
+}
+
+type BatchCreateItemsRequest struct {
+	Mode            string              `json:"mode"             validate:"omitempty,oneof=atomic best_effort"`
+	AllowDuplicates bool                `json:"allow_duplicates"`
+	Items           []CreateItemRequest `json:"items"            validate:"required,min=1,max=10000"`
+}
+
+// ImportOptions are accepted by every import. Location reads dates without
+// an offset.
+type ImportOptions struct {
+	DryRun          bool           `json:"dry_run"`
+	AllowDuplicates bool           `json:"allow_duplicates"`
+	Location        *time.Location `json:"-"`
+}
+
+type ImportItemsRequest struct {
+	ImportOptions
+
+	Mode string `json:"mode" validate:"omitempty,oneof=atomic best_effort"`
+	// RowIDNamespace, when set, gives rows without an id an ID derived from
+	// their line, so importing the same file again writes the same items.
+	RowIDNamespace uuid.UUID `json:"-"`
+}
+
+type ImportStatementRequest struct {
+	ImportOptions
+
+	Format string `json:"format" validate:"required,oneof=1c ofx qif camt053"`
+}
+
+type ImportWBReportRequest struct {
+	ImportOptions
+
+	ReportID int64 `json:"report_id" validate:"gte=0"`
+}
+
+// CreateImportJobRequest enqueues an import of Type with the options of the
+// matching import endpoint; only the options of Type are set.
+type CreateImportJobRequest struct {
+	Type      string                  `json:"type"                validate:"required,oneof=csv statement wb"`
+	Items     *ImportItemsRequest     `json:"items,omitempty"`
+	Statement *ImportStatementRequest `json:"statement,omitempty"`
+	WBReport  *ImportWBReportRequest  `json:"wb_report,omitempty"`
+}
+
+type ImportItemRequest struct {
+	ID         string `json:"id"          validate:"omitempty,uuid"`
+	Type       string `json:"type"        validate:"required,item_type"`
+	Amount     int    `json:"amount"      validate:"required,gt=0,lte=2147483647"`
+	Date       string `json:"date"        validate:"required,rfc3339"`
+	Category   string `json:"category"    validate:"required,min=3,max=32"`
+	Source     string `json:"source"      validate:"required_with=ExternalID,max=32"`
+	ExternalID string `json:"external_id" validate:"required_with=Source,max=128"`
 }
 
 type GetItemsRequest struct {
-	From      *time.Time `json:"from,omitempty"`
-	To        *time.Time `json:"to,omitempty"`
-	Type      *string    `json:"type,omitempty"       validate:"omitempty,item_type"`
-	Category  *string    `json:"category,omitempty"`
-	SortBy    *string    `json:"sort_by,omitempty"    validate:"omitempty,sort_by"`
-	SortOrder *string    `json:"sort_order,omitempty" validate:"omitempty,sort_order"`
+	From      *time.Time   `json:"from,omitempty"`
+	To        *time.Time   `json:"to,omitempty"`
+	Type      *string      `json:"type,omitempty"       validate:"omitempty,item_type"`
+	Category  *string      `json:"category,omitempty"`
+	SortBy    *string      `json:"sort_by,omitempty"    validate:"omitempty,sort_by"`
+	SortOrder *string      `json:"sort_order,omitempty" validate:"omitempty,sort_order"`
+	Limit     *int         `json:"limit,omitempty"      validate:"omitempty,min=1,max=1000"`
+	Offset    *int         `json:"offset,omitempty"     validate:"omitempty,min=0"`
+	Cursor    *ItemsCursor `json:"cursor,omitempty"`
+}
+
+type ExportItemsRequest struct {
+	GetItemsRequest
+
+	Format   string         `json:"format"            validate:"oneof=csv xlsx json ndjson zip 1c ofx qif"`
+	Columns  []string       `json:"columns,omitempty"`
+	Location *time.Location `json:"-"`
+
+	CSVDialect
+}
+
+// CSVDialect overrides the named dialect preset field by field.
+type CSVDialect struct {
+	Dialect    *string `json:"dialect,omitempty"     validate:"omitempty,oneof=default excel-ru"`
+	Delimiter  *rune   `json:"delimiter,omitempty"`
+	BOM        *bool   `json:"bom,omitempty"`
+	Decimal    *string `json:"decimal,omitempty"`
+	DateLayout *string `json:"date_layout,omitempty" validate:"omitempty,max=64"`
+	HeaderLang *string `json:"header_lang,omitempty" validate:"omitempty,oneof=en ru"`
+}
+
+type ItemsCursor struct {
+	Date time.Time `json:"d"`
+	ID   uuid.UUID `json:"id"`
 }
 
 type UpdateItemRequest struct {
 	Type     *string    `json:"type,omitempty"     validate:"omitempty,item_type"`
-	Amount   *int       `json:"amount,omitempty"   validate:"omitempty,gt=0"`
+	Amount   *int       `json:"amount,omitempty"   validate:"omitempty,gt=0,lte=2147483647"`
 	Date     *time.Time `json:"date,omitempty"     validate:"omitempty,rfc3339"`
-	Category *string    `json:"category,omitempty" validate:"omitempty,min=3"`
+	Category *string    `json:"category,omitempty" validate:"omitempty,min=3,max=32"`
 }
 
 type UpdateItemRequestInput struct {
 	Type     *string `json:"type,omitempty"     validate:"omitempty,item_type"`
-	Amount   *int    `json:"amount,omitempty"   validate:"omitempty,gt=0"`
+	Amount   *int    `json:"amount,omitempty"   validate:"omitempty,gt=0,lte=2147483647"`
 	Date     *string `json:"date,omitempty"     validate:"omitempty,rfc3339"`
-	Category *string `json:"category,omitempty" validate:"omitempty,min=3"`
+	Category *string `json:"category,omitempty" validate:"omitempty,min=3,max=32"`
 }
 
 type AnalyticsRequest struct {
-	From    *time.Time `json:"from,omitempty"`
-	To      *time.Time `json:"to,omitempty"`
-	GroupBy *string    `json:"group_by,omitempty"`
+	From     *time.Time     `json:"from,omitempty"`
+	To       *time.Time     `json:"to,omitempty"`
+	Type     *string        `json:"type,omitempty"     validate:"omitempty,item_type"`
+	GroupBy  *string        `json:"group_by,omitempty" validate:"omitempty,group_by"`
+	Fill     *string        `json:"fill,omitempty"     validate:"omitempty,oneof=zero null none"`
+	Compare  *string        `json:"compare,omitempty"  validate:"omitempty,oneof=previous_period previous_year"`
+	Location *time.Location `json:"-"`
+	// Category narrows the aggregation to one category. It is not a query
+	// parameter of /api/analytics and is set by exports built on an items filter.
+	Category *string `json:"-"`
+}
+
+type AnalyticsExportRequest struct {
+	AnalyticsRequest
+
+	Format string `json:"format" validate:"oneof=csv xlsx json"`
+}
+
+type PivotRequest struct {
+	From     *time.Time     `json:"from,omitempty"`
+	To       *time.Time     `json:"to,omitempty"`
+	Type     *string        `json:"type,omitempty"     validate:"omitempty,item_type"`
+	Rows     []string       `json:"rows"               validate:"min=1,max=3,dive,pivot_dimension"`
+	Columns  []string       `json:"columns"            validate:"min=1,max=3,dive,pivot_dimension"`
+	Measures []string       `json:"measures"           validate:"min=1,dive,pivot_measure"`
+	Location *time.Location `json:"-"`
 }
@@ -1,36 +1,240 @@
 package dto
 
+import "encoding/json"
+
 type ItemResponse struct {
-	ID        string `json:"id,omitempty"`
-	Type      string `json:"type,omitempty"`
-	Amount    string `json:"amount,omitempty"`
-	Date      string `json:"date,omitempty"`
-	Category  string `json:"category,omitempty"`
-	CreatedAt string `json:"created_at,omitempty"`
-	UpdatedAt string `json:"updated_at,omitempty"`
+	ID         string `json:"id,omitempty"`
+	Type       string `json:"type,omitempty"`
+	Amount     string `json:"amount,omitempty"`
+	Date       string `json:"date,omitempty"`
+	Category   string `json:"category,omitempty"`
+	Source     string `json:"source,omitempty"`
+	ExternalID string `json:"external_id,omitempty"`
+	CreatedAt  string `json:"created_at,omitempty"`
+	UpdatedAt  string `json:"updated_at,omitempty"`
+}
+
+// ExportManifest describes the files of an accounting archive export.
+type ExportManifest struct {
+	GeneratedAt string               `json:"generated_at"`
+	Filter      ExportManifestFilter `json:"filter"`
+	Files       []ExportManifestFile `json:"files"`
+}
+
+type ExportManifestFilter struct {
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	Timezone string  `json:"tz"`
+	Type     *string `json:"type,omitempty"`
+	Category *string `json:"category,omitempty"`
+}
+
+type ExportManifestFile struct {
+	Name   string `json:"name"`
+	Rows   int    `json:"rows"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
 }
 
 type ItemsListResponse struct {
-	Items []ItemResponse `json:"items"`
-	Total int            `json:"total"`
+	Items      []ItemResponse `json:"items"`
+	Total      int            `json:"total"`
+	NextCursor *string        `json:"next_cursor,omitempty"`
+}
+
+type BatchCreateItemsResponse struct {
+	Created             int                  `json:"created"`
+	Updated             int                  `json:"updated"`
+	Failed              int                  `json:"failed"`
+	Suspected           int                  `json:"suspected"`
+	Items               []ItemResponse       `json:"items"`
+	Errors              []BatchItemError     `json:"errors,omitempty"`
+	SuspectedDuplicates []SuspectedDuplicate `json:"suspected_duplicates,omitempty"`
+}
+
+type BatchItemError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+type ImportItemsResponse struct {
+	DryRun              bool                 `json:"dry_run"`
+	Total               int                  `json:"total"`
+	Valid               int                  `json:"valid"`
+	Failed              int                  `json:"failed"`
+	Inserted            int                  `json:"inserted"`
+	Updated             int                  `json:"updated"`
+	Suspected           int                  `json:"suspected"`
+	Errors              []ImportLineError    `json:"errors,omitempty"`
+	SuspectedDuplicates []SuspectedDuplicate `json:"suspected_duplicates,omitempty"`
+}
+
+// ImportStatementResponse reports how the transactions of a bank statement
+// were handled. Skipped records are explained in Errors and suspected
+// duplicates in SuspectedDuplicates; a dry run lists the items that would be
+// inserted in Items.
+type ImportStatementResponse struct {
+	DryRun              bool                 `json:"dry_run"`
+	Parsed              int                  `json:"parsed"`
+	Skipped             int                  `json:"skipped"`
+	Duplicates          int                  `json:"duplicates"`
+	Inserted            int                  `json:"inserted"`
+	Suspected           int                  `json:"suspected"`
+	Errors              []ImportLineError    `json:"errors,omitempty"`
+	Items               []ItemResponse       `json:"items,omitempty"`
+	SuspectedDuplicates []SuspectedDuplicate `json:"suspected_duplicates,omitempty"`
+}
+
+// SuspectedDuplicate is an item that was not written because stored items
+// of the same type, amount and category are dated close to it. Line is set
+// for imports that report lines.
+// SuspectedDuplicate is an item held back as a possible duplicate, with its
+// line in an imported file or its index in a batch.
+type SuspectedDuplicate struct {
+	Line        int          `json:"line,omitempty"`
+	Index       *int         `json:"index,omitempty"`
+	Item        ItemResponse `json:"item"`
+	DuplicateOf []string     `json:"duplicate_of"`
+}
+
+type ImportLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
 }
 
 type AnalyticsResponse struct {
-	From         string             `json:"from,omitempty"`
-	To           string             `json:"to,omitempty"`
-	Sum          float64            `json:"sum"`
-	Avg          *float64           `json:"avg,omitempty"`
-	Count        int                `json:"count"`
-	Median       *float64           `json:"median,omitempty"`
-	Percentile90 *float64           `json:"percentile_90,omitempty"`
-	Grouped      []GroupedAnalytics `json:"grouped,omitempty"`
+	From         string               `json:"from,omitempty"`
+	To           string               `json:"to,omitempty"`
+	Timezone     string               `json:"tz,omitempty"`
+	Fill         string               `json:"fill,omitempty"`
+	Sum          float64              `json:"sum"`
+	Avg          *float64             `json:"avg,omitempty"`
+	Count        int                  `json:"count"`
+	Median       *float64             `json:"median,omitempty"`
+	Percentile90 *float64             `json:"percentile_90,omitempty"`
+	Income       float64              `json:"income"`
+	Expense      float64              `json:"expense"`
+	Net          float64              `json:"net"`
+	IncomeCount  int                  `json:"income_count"`
+	ExpenseCount int                  `json:"expense_count"`
+	Grouped      []GroupedAnalytics   `json:"grouped,omitempty"`
+	Comparison   *AnalyticsComparison `json:"comparison,omitempty"`
+}
+
+// AnalyticsExportRow is one line of an analytics file export: a grouped
+// bucket or the totals. The previous_* and *_change_pct columns are filled
+// only when a comparison was requested.
+type AnalyticsExportRow struct {
+	Group         string   `csv:"group"`
+	Sum           *float64 `csv:"sum"`
+	Avg           *float64 `csv:"avg"`
+	Count         int      `csv:"count"`
+	Median        *float64 `csv:"median"`
+	Percentile90  *float64 `csv:"percentile_90"`
+	Income        *float64 `csv:"income"`
+	Expense       *float64 `csv:"expense"`
+	Net           *float64 `csv:"net"`
+	IncomeCount   int      `csv:"income_count"`
+	ExpenseCount  int      `csv:"expense_count"`
+	PreviousGroup string   `csv:"previous_group"`
+	SumPrevious   *float64 `csv:"sum_previous"`
+	SumChange     *float64 `csv:"sum_change_pct,format=%.2f"`
+	CountPrevious *int     `csv:"count_previous"`
+	CountChange   *float64 `csv:"count_change_pct,format=%.2f"`
+	IncomePrev    *float64 `csv:"income_previous"`
+	IncomeChange  *float64 `csv:"income_change_pct,format=%.2f"`
+	ExpensePrev   *float64 `csv:"expense_previous"`
+	ExpenseChange *float64 `csv:"expense_change_pct,format=%.2f"`
+	NetPrevious   *float64 `csv:"net_previous"`
+	NetChange     *float64 `csv:"net_change_pct,format=%.2f"`
+}
+
+type AnalyticsComparison struct {
+	Mode    string                 `json:"mode"`
+	From    string                 `json:"from"`
+	To      string                 `json:"to"`
+	Metrics map[string]MetricDelta `json:"metrics"`
+}
+
+type MetricDelta struct {
+	Previous *float64 `json:"previous"`
+	Absolute *float64 `json:"absolute"`
+	Percent  *float64 `json:"percent"`
 }
 
 type GroupedAnalytics struct {
-	Group        string   `json:"group"`
-	Sum          *float64 `json:"sum,omitempty"`
-	Avg          *float64 `json:"avg,omitempty"`
-	Count        int      `json:"count"`
-	Median       *float64 `json:"median,omitempty"`
-	Percentile90 *float64 `json:"percentile90,omitempty"`
+	Group         string                 `json:"group"`
+	Sum           *float64               `json:"sum,omitempty"`
+	Avg           *float64               `json:"avg,omitempty"`
+	Count         int                    `json:"count"`
+	Median        *float64               `json:"median,omitempty"`
+	Percentile90  *float64               `json:"percentile90,omitempty"`
+	Income        *float64               `json:"income"`
+	Expense       *float64               `json:"expense"`
+	Net           *float64               `json:"net"`
+	IncomeCount   int                    `json:"income_count"`
+	ExpenseCount  int                    `json:"expense_count"`
+	PreviousGroup string                 `json:"previous_group,omitempty"`
+	Comparison    map[string]MetricDelta `json:"comparison,omitempty"`
+}
+
+type PivotResponse struct {
+	From         string          `json:"from,omitempty"`
+	To           string          `json:"to,omitempty"`
+	Timezone     string          `json:"tz,omitempty"`
+	Rows         []string        `json:"rows"`
+	Columns      []string        `json:"columns"`
+	Measures     []string        `json:"measures"`
+	RowKeys      [][]string      `json:"row_keys"`
+	ColumnKeys   [][]string      `json:"column_keys"`
+	Cells        [][]PivotValues `json:"cells"`
+	RowTotals    []PivotValues   `json:"row_totals"`
+	ColumnTotals []PivotValues   `json:"column_totals"`
+	GrandTotal   PivotValues     `json:"grand_total"`
+}
+
+type PivotValues map[string]*float64
+
+// WBSyncStatusResponse reports the state of the WB statistics API sync.
+// Sources lists every configured source, including those not synced yet.
+type WBSyncStatusResponse struct {
+	Enabled   bool                 `json:"enabled"`
+	Running   bool                 `json:"running"`
+	Interval  string               `json:"interval"`
+	NextRunAt string               `json:"next_run_at,omitempty"`
+	Sources   []WBSyncSourceStatus `json:"sources"`
+}
+
+type WBSyncSourceStatus struct {
+	Source            string `json:"source"`
+	LastChangeAt      string `json:"last_change_at,omitempty"`
+	LastSyncAt        string `json:"last_sync_at,omitempty"`
+	LastSuccessAt     string `json:"last_success_at,omitempty"`
+	LastError         string `json:"last_error,omitempty"`
+	LastCount         int    `json:"last_count"`
+	LastSkipped       int    `json:"last_skipped"`
+	LastSkippedReason string `json:"last_skipped_reason,omitempty"`
+}
+
+// JobResponse reports the state of an import job. Progress is an estimate
+// in percent over the phases of the job: reading the file, processing and
+// writing its rows. Result is the response of the import endpoint, with its
+// row errors, once the job has run.
+type JobResponse struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Status        string          `json:"status"`
+	Progress      int             `json:"progress"`
+	Phase         string          `json:"phase,omitempty"`
+	ReadBytes     int64           `json:"read_bytes"`
+	TotalBytes    int64           `json:"total_bytes"`
+	ProcessedRows int             `json:"processed_rows"`
+	TotalRows     int             `json:"total_rows"`
+	Attempts      int             `json:"attempts"`
+	Error         string          `json:"error,omitempty"`
+	Result        json.RawMessage `json:"result,omitempty"`
+	CreatedAt     string          `json:"created_at"`
+	StartedAt     string          `json:"started_at,omitempty"`
+	FinishedAt    string          `json:"finished_at,omitempty"`
+	UpdatedAt     string          `json:"updated_at"`
 }
@@ -1,20 +1,54 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
 	"github.com/kstsm/wb-sales-tracker/internal/dto"
 )
 
 func (h *Handler) getAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
 	var req dto.AnalyticsRequest
 
-	if err := parseAnalyticsQuery(r, &req); err != nil {
+	if err := parseAnalyticsQuery(r, &req, h.analytics.Location); err != nil {
 		h.respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
 	result, err := h.service.GetAnalytics(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrTooManyGroups):
+			h.respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.respondError(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) getPivotHandler(w http.ResponseWriter, r *http.Request) {
+	var req dto.PivotRequest
+
+	if err := parsePivotQuery(r, &req, h.analytics.Location); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
+	result, err := h.service.GetPivot(r.Context(), req)
 	if err != nil {
 		h.respondError(w, http.StatusInternalServerError, "internal server error")
 		return
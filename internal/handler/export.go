@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/pkg/export"
+)
+
+const defaultExportFormat = "csv"
+
+// exportFormat describes how a file export of a request type R is rendered.
+type exportFormat[R any] struct {
+	contentType string
+	filename    string
+	write       func(ctx context.Context, w io.Writer, req R) error
+}
+
+func (h *Handler) exportFormats() map[string]exportFormat[dto.ExportItemsRequest] {
+	return map[string]exportFormat[dto.ExportItemsRequest]{
+		"csv":    {"text/csv", "items.csv", h.service.ExportItemsCSV},
+		"xlsx":   {export.XLSXContentType, "items.xlsx", h.service.ExportItemsXLSX},
+		"json":   {"application/json", "items.json", h.service.ExportItemsJSON},
+		"ndjson": {"application/x-ndjson", "items.ndjson", h.service.ExportItemsNDJSON},
+		"zip":    {"application/zip", "accounting.zip", h.service.ExportItemsZIP},
+		"1c":     {export.OneCContentType, "kl_to_1c.txt", h.service.ExportItemsOneC},
+		"ofx":    {export.OFXContentType, "items.ofx", h.service.ExportItemsOFX},
+		"qif":    {export.QIFContentType, "items.qif", h.service.ExportItemsQIF},
+	}
+}
+
+func (h *Handler) analyticsExportFormats() map[string]exportFormat[dto.AnalyticsRequest] {
+	return map[string]exportFormat[dto.AnalyticsRequest]{
+		"csv":  {"text/csv", "analytics.csv", h.service.ExportAnalyticsCSV},
+		"xlsx": {export.XLSXContentType, "analytics.xlsx", h.service.ExportAnalyticsXLSX},
+		"json": {"application/json", "analytics.json", h.service.ExportAnalyticsJSON},
+	}
+}
+
+func (h *Handler) exportItemsHandler(w http.ResponseWriter, r *http.Request) {
+	var req dto.ExportItemsRequest
+
+	if err := parseExportQuery(r, &req, h.analytics.Location); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	formats := h.exportFormats()
+
+	if req.Format == "" {
+		format, ok := negotiateExportFormat(r.Header.Get("Accept"), formats)
+		if !ok {
+			h.respondError(w, http.StatusNotAcceptable, "none of the accepted media types can be exported")
+			return
+		}
+		req.Format = format
+	}
+
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
+	if err := checkExportOptions(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := formats[req.Format]
+	w.Header().Add("Vary", "Accept")
+
+	stream := newStreamWriter(w, format.contentType, format.filename)
+	err := format.write(r.Context(), stream, req)
+	switch {
+	case err == nil:
+	case (errors.Is(err, export.ErrUnknownColumn) || errors.Is(err, apperrors.ErrTooManyGroups)) && !stream.started:
+		h.respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, apperrors.ErrNotConfigured) && !stream.started:
+		h.respondError(w, http.StatusNotImplemented, err.Error())
+	default:
+		h.respondStreamError(w, r, stream, err)
+	}
+}
+
+// checkExportOptions rejects parameters that the requested format ignores.
+func checkExportOptions(req dto.ExportItemsRequest) error {
+	tabular := req.Format == "csv" || req.Format == "zip"
+
+	if len(req.Columns) > 0 && !tabular && req.Format != "xlsx" {
+		return errors.New("parameter 'columns' is supported only for csv, xlsx and zip")
+	}
+
+	if req.CSVDialect != (dto.CSVDialect{}) && !tabular {
+		return errors.New("dialect parameters are supported only for csv and zip")
+	}
+
+	// encoding/csv only checks the delimiter on the first write, after the
+	// response has started.
+	if req.Delimiter != nil && !export.ValidDelimiter(*req.Delimiter) {
+		return errors.New("parameter 'delimiter' must be a single character other than a quote or a line break")
+	}
+
+	needsPeriod := req.Format == "zip" || req.Format == "1c" || req.Format == "ofx"
+	if needsPeriod && (req.From == nil || req.To == nil) {
+		return fmt.Errorf("parameters 'from' and 'to' are required for format=%s", req.Format)
+	}
+
+	return nil
+}
+
+func (h *Handler) exportAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	var req dto.AnalyticsExportRequest
+
+	if err := parseAnalyticsExportQuery(r, &req, h.analytics.Location); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	formats := h.analyticsExportFormats()
+
+	if req.Format == "" {
+		format, ok := negotiateExportFormat(r.Header.Get("Accept"), formats)
+		if !ok {
+			h.respondError(w, http.StatusNotAcceptable, "none of the accepted media types can be exported")
+			return
+		}
+		req.Format = format
+	}
+
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
+	format := formats[req.Format]
+	w.Header().Add("Vary", "Accept")
+
+	stream := newStreamWriter(w, format.contentType, format.filename)
+	err := format.write(r.Context(), stream, req.AnalyticsRequest)
+	switch {
+	case err == nil:
+	case errors.Is(err, apperrors.ErrTooManyGroups) && !stream.started:
+		h.respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.respondStreamError(w, r, stream, err)
+	}
+}
+
+// negotiateExportFormat picks the export format for an Accept header, trying
+// media ranges from the highest q-value down. An empty header or a wildcard
+// selects CSV.
+func negotiateExportFormat[R any](accept string, formats map[string]exportFormat[R]) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return defaultExportFormat, true
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+
+	var ranges []mediaRange
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if qStr, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qStr, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+
+	slices.SortStableFunc(ranges, func(a, b mediaRange) int {
+		return cmp.Compare(b.q, a.q)
+	})
+
+	for _, rng := range ranges {
+		switch rng.mediaType {
+		case "*/*", "text/*":
+			return defaultExportFormat, true
+		case "application/*":
+			return "json", true
+		case "application/ndjson":
+			if _, ok := formats["ndjson"]; ok {
+				return "ndjson", true
+			}
+		}
+
+		for name, format := range formats {
+			if format.contentType == rng.mediaType {
+				return name, true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/gookit/slog"
+	"github.com/kstsm/wb-sales-tracker/config"
 	"github.com/kstsm/wb-sales-tracker/internal/middleware"
 	"github.com/kstsm/wb-sales-tracker/internal/service"
 	"github.com/kstsm/wb-sales-tracker/pkg/validator"
@@ -15,16 +16,29 @@ type ItemManager interface {
 }
 
 type Handler struct {
-	service service.ItemManager
-	log     *slog.Logger
-	valid   *validator.Validate
+	service   service.ItemManager
+	wbSync    service.WBSyncManager
+	jobs      service.JobManager
+	log       *slog.Logger
+	valid     *validator.Validate
+	analytics config.Analytics
 }
 
-func NewHandler(service service.ItemManager, log *slog.Logger, valid *validator.Validate) ItemManager {
+func NewHandler(
+	service service.ItemManager,
+	wbSync service.WBSyncManager,
+	jobs service.JobManager,
+	log *slog.Logger,
+	valid *validator.Validate,
+	analytics config.Analytics,
+) ItemManager {
 	return &Handler{
-		service: service,
-		log:     log,
-		valid:   valid,
+		service:   service,
+		wbSync:    wbSync,
+		jobs:      jobs,
+		log:       log,
+		valid:     valid,
+		analytics: analytics,
 	}
 }
 
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+)
+
+const maxImportSize = 64 << 20
+
+func (h *Handler) importItemsCSVHandler(w http.ResponseWriter, r *http.Request) {
+	var req dto.ImportItemsRequest
+
+	if err := parseImportQuery(r, &req, h.analytics.Location); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
+	h.serveImport(w, r, "importItemsCSVHandler", func(ctx context.Context, file io.Reader) (any, int, error) {
+		result, err := h.service.ImportItemsCSV(ctx, file, req)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		status := http.StatusOK
+		if !req.DryRun && result.Failed > 0 && req.Mode != "best_effort" {
+			status = http.StatusBadRequest
+		}
+
+		return result, status, nil
+	})
+}
+
+func (h *Handler) importStatementHandler(w http.ResponseWriter, r *http.Request) {
+	var req dto.ImportStatementRequest
+
+	if err := parseStatementImportQuery(r, &req, h.analytics.Location); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
+	h.serveImport(w, r, "importStatementHandler", func(ctx context.Context, file io.Reader) (any, int, error) {
+		result, err := h.service.ImportStatement(ctx, file, req)
+		return result, http.StatusOK, err
+	})
+}
+
+func (h *Handler) importWBReportHandler(w http.ResponseWriter, r *http.Request) {
+	var req dto.ImportWBReportRequest
+
+	if err := parseWBImportQuery(r, &req, h.analytics.Location); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
+	h.serveImport(w, r, "importWBReportHandler", func(ctx context.Context, file io.Reader) (any, int, error) {
+		result, err := h.service.ImportWBReport(ctx, file, req)
+		return result, http.StatusOK, err
+	})
+}
+
+// serveImport limits the request body, passes the uploaded file to run and
+// responds with the result and status it returns. Errors other than an
+// invalid or oversized file are logged under name.
+func (h *Handler) serveImport(
+	w http.ResponseWriter,
+	r *http.Request,
+	name string,
+	run func(ctx context.Context, file io.Reader) (any, int, error),
+) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	file, err := importFile(r)
+	if err != nil {
+		h.respondImportFileError(w, err)
+		return
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			h.log.Errorf("%s: %v", name, closeErr)
+		}
+	}()
+
+	result, status, err := run(r.Context(), file)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			h.respondError(w, http.StatusRequestEntityTooLarge, "import file is too large")
+		case errors.Is(err, apperrors.ErrInvalidImport):
+			h.respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.log.Errorf("%s: %v", name, err)
+			h.respondError(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+
+	h.respondJSON(w, status, result)
+}
+
+func (h *Handler) respondImportFileError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		h.respondError(w, http.StatusRequestEntityTooLarge, "import file is too large")
+		return
+	}
+
+	h.respondError(w, http.StatusBadRequest, err.Error())
+}
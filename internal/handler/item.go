@@ -22,13 +22,75 @@ func (h *Handler) createItemHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.CreateItem(r.Context(), req)
+	result, created, err := h.service.CreateItem(r.Context(), req)
 	if err != nil {
+		if errors.Is(err, apperrors.ErrDuplicate) {
+			h.respondError(w, http.StatusConflict, err.Error())
+			return
+		}
 		h.respondError(w, http.StatusBadRequest, "internal server error")
 		return
 	}
 
+	status := http.StatusCreated
+	if !created {
+		status = http.StatusOK
+	}
+
 	resp := converter.ItemToResponse(result)
+	h.respondJSON(w, status, resp)
+}
+
+func (h *Handler) createItemsBatchHandler(w http.ResponseWriter, r *http.Request) {
+	var req dto.BatchCreateItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
+	resp := dto.BatchCreateItemsResponse{Items: []dto.ItemResponse{}}
+	valid := make([]dto.CreateItemRequest, 0, len(req.Items))
+	indexes := make([]int, 0, len(req.Items))
+	for i, item := range req.Items {
+		if err := h.valid.Struct(item); err != nil {
+			resp.Errors = append(resp.Errors, dto.BatchItemError{
+				Index: i,
+				Error: h.valid.FormatValidationError(err),
+			})
+			continue
+		}
+		valid = append(valid, item)
+		indexes = append(indexes, i)
+	}
+	resp.Failed = len(resp.Errors)
+
+	if len(valid) == 0 || (resp.Failed > 0 && req.Mode != "best_effort") {
+		h.respondJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	result, created, duplicates, err := h.service.CreateItems(r.Context(), valid, req.AllowDuplicates)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	// The service reports positions among the valid items.
+	for i := range duplicates {
+		index := indexes[*duplicates[i].Index]
+		duplicates[i].Index = &index
+	}
+
+	resp.Created = created
+	resp.Updated = len(result) - created
+	resp.Suspected = len(duplicates)
+	resp.SuspectedDuplicates = duplicates
+	resp.Items = converter.ItemsToResponse(result)
 	h.respondJSON(w, http.StatusCreated, resp)
 }
 
@@ -75,8 +137,9 @@ func (h *Handler) getItemsHandler(w http.ResponseWriter, r *http.Request) {
 
 	resp := converter.ItemsToResponse(result)
 	h.respondJSON(w, http.StatusOK, dto.ItemsListResponse{
-		Items: resp,
-		Total: total,
+		Items:      resp,
+		Total:      total,
+		NextCursor: converter.ItemsNextCursor(result, req),
 	})
 }
 
@@ -133,30 +196,3 @@ func (h *Handler) deleteItemHandler(w http.ResponseWriter, r *http.Request) {
 
 	h.respondJSON(w, http.StatusOK, nil)
 }
-
-func (h *Handler) exportItemCSVHandler(w http.ResponseWriter, r *http.Request) {
-	var req dto.GetItemsRequest
-
-	if err := parseGetItemsQuery(r, &req); err != nil {
-		h.respondError(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	if err := h.valid.Struct(req); err != nil {
-		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
-		return
-	}
-
-	data, err := h.service.ExportItemsCSV(r.Context(), req)
-	if err != nil {
-		switch {
-		case errors.Is(err, apperrors.ErrItemNotFound):
-			h.respondError(w, http.StatusNotFound, "item not found")
-		default:
-			h.respondError(w, http.StatusInternalServerError, "internal server error")
-		}
-		return
-	}
-
-	h.respondCSV(w, http.StatusOK, data)
-}
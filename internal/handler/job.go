@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+)
+
+func (h *Handler) createCSVImportJobHandler(w http.ResponseWriter, r *http.Request) {
+	var req dto.ImportItemsRequest
+
+	if err := parseImportQuery(r, &req, h.analytics.Location); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
+	h.enqueueImportJob(w, r, dto.CreateImportJobRequest{Type: "csv", Items: &req})
+}
+
+func (h *Handler) createStatementImportJobHandler(w http.ResponseWriter, r *http.Request) {
+	var req dto.ImportStatementRequest
+
+	if err := parseStatementImportQuery(r, &req, h.analytics.Location); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
+	h.enqueueImportJob(w, r, dto.CreateImportJobRequest{Type: "statement", Statement: &req})
+}
+
+func (h *Handler) createWBImportJobHandler(w http.ResponseWriter, r *http.Request) {
+	var req dto.ImportWBReportRequest
+
+	if err := parseWBImportQuery(r, &req, h.analytics.Location); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
+	h.enqueueImportJob(w, r, dto.CreateImportJobRequest{Type: "wb", WBReport: &req})
+}
+
+// enqueueImportJob stores the uploaded file as a job and responds with 202
+// and the job's address in Location.
+func (h *Handler) enqueueImportJob(w http.ResponseWriter, r *http.Request, req dto.CreateImportJobRequest) {
+	if err := h.valid.Struct(req); err != nil {
+		h.respondError(w, http.StatusBadRequest, h.valid.FormatValidationError(err))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	file, err := importFile(r)
+	if err != nil {
+		h.respondImportFileError(w, err)
+		return
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			h.log.Errorf("enqueueImportJob: %v", closeErr)
+		}
+	}()
+
+	job, err := h.jobs.EnqueueImport(r.Context(), file, req)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			h.respondError(w, http.StatusRequestEntityTooLarge, "import file is too large")
+		default:
+			h.log.Errorf("enqueueImportJob: %v", err)
+			h.respondError(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/api/jobs/"+job.ID.String())
+	h.respondJSON(w, http.StatusAccepted, converter.JobToResponse(job))
+}
+
+func (h *Handler) getJobHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUUIDParam(r, "id")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := h.jobs.GetJob(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrJobNotFound):
+			h.respondError(w, http.StatusNotFound, "job not found")
+		default:
+			h.log.Errorf("getJobHandler: %v", err)
+			h.respondError(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, converter.JobToResponse(job))
+}
+
+func (h *Handler) cancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUUIDParam(r, "id")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := h.jobs.CancelJob(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrJobNotFound):
+			h.respondError(w, http.StatusNotFound, "job not found")
+		case errors.Is(err, apperrors.ErrJobFinished):
+			h.respondError(w, http.StatusConflict, err.Error())
+		default:
+			h.log.Errorf("cancelJobHandler: %v", err)
+			h.respondError(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, converter.JobToResponse(job))
+}
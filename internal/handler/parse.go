@@ -3,16 +3,24 @@ package handler
 import (
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
 	"github.com/kstsm/wb-sales-tracker/internal/dto"
 )
 
+const defaultItemsLimit = 100
+
 func parseUUIDParam(r *http.Request, param string) (uuid.UUID, error) {
 	value := chi.URLParam(r, param)
 	if strings.TrimSpace(value) == "" {
@@ -30,21 +38,140 @@ func parseUUIDParam(r *http.Request, param string) (uuid.UUID, error) {
 func parseGetItemsQuery(r *http.Request, req *dto.GetItemsRequest) error {
 	q := r.URL.Query()
 
-	allowedParams := map[string]bool{
-		"from":       true,
-		"to":         true,
-		"type":       true,
-		"category":   true,
-		"sort_by":    true,
-		"sort_order": true,
+	if err := checkAllowedParams(q, itemsFilterParams, "limit", "offset", "cursor"); err != nil {
+		return err
+	}
+
+	if err := parseItemsFilter(q, req); err != nil {
+		return err
+	}
+
+	return parsePagination(q, req)
+}
+
+func parseExportQuery(r *http.Request, req *dto.ExportItemsRequest, defaultLocation *time.Location) error {
+	q := r.URL.Query()
+
+	if err := checkAllowedParams(q, itemsFilterParams, exportParams...); err != nil {
+		return err
+	}
+
+	if err := parseItemsFilter(q, &req.GetItemsRequest); err != nil {
+		return err
+	}
+
+	if formatStr := strings.ToLower(strings.TrimSpace(q.Get("format"))); formatStr != "" {
+		req.Format = formatStr
+	} else if q.Has("format") {
+		return errors.New("parameter 'format' cannot be empty")
+	}
+
+	if q.Has("columns") {
+		req.Columns = parseList(q.Get("columns"))
+		if len(req.Columns) == 0 {
+			return errors.New("parameter 'columns' cannot be empty")
+		}
+
+		seen := make(map[string]bool)
+		for _, col := range req.Columns {
+			if seen[col] {
+				return fmt.Errorf("column '%s' is used more than once", col)
+			}
+			seen[col] = true
+		}
+	}
+
+	location, err := parseLocation(q, defaultLocation)
+	if err != nil {
+		return err
+	}
+	req.Location = location
+
+	return parseCSVDialect(q, &req.CSVDialect)
+}
+
+var (
+	dialectParams = []string{"dialect", "delimiter", "bom", "decimal", "date_layout", "header_lang"}
+	exportParams  = append([]string{"format", "columns", "tz"}, dialectParams...)
+
+	// delimiterAliases spell out delimiters that are awkward in a query
+	// string; a bare ';' is not accepted by net/url.
+	delimiterAliases = map[string]string{
+		"tab":       "\t",
+		"semicolon": ";",
+		"comma":     ",",
+		"pipe":      "|",
+	}
+)
+
+func parseCSVDialect(q url.Values, d *dto.CSVDialect) error {
+	for _, param := range dialectParams {
+		// A space is a valid delimiter, so only its length is checked below.
+		if param != "delimiter" && q.Has(param) && strings.TrimSpace(q.Get(param)) == "" {
+			return fmt.Errorf("parameter '%s' cannot be empty", param)
+		}
+	}
+
+	if q.Has("dialect") {
+		dialect := strings.ToLower(strings.TrimSpace(q.Get("dialect")))
+		d.Dialect = &dialect
+	}
+
+	if q.Has("delimiter") {
+		delimiter := q.Get("delimiter")
+		if alias, ok := delimiterAliases[strings.ToLower(delimiter)]; ok {
+			delimiter = alias
+		}
+
+		runes := []rune(delimiter)
+		if len(runes) != 1 {
+			return errors.New("parameter 'delimiter' must be a single character")
+		}
+		d.Delimiter = &runes[0]
 	}
 
+	if q.Has("bom") {
+		bom, err := strconv.ParseBool(strings.TrimSpace(q.Get("bom")))
+		if err != nil {
+			return errors.New("parameter 'bom' must be a boolean")
+		}
+		d.BOM = &bom
+	}
+
+	if q.Has("decimal") {
+		decimal := strings.TrimSpace(q.Get("decimal"))
+		if decimal != "." && decimal != "," {
+			return errors.New("parameter 'decimal' must be '.' or ','")
+		}
+		d.Decimal = &decimal
+	}
+
+	if q.Has("date_layout") {
+		layout := q.Get("date_layout")
+		d.DateLayout = &layout
+	}
+
+	if q.Has("header_lang") {
+		lang := strings.ToLower(strings.TrimSpace(q.Get("header_lang")))
+		d.HeaderLang = &lang
+	}
+
+	return nil
+}
+
+var itemsFilterParams = []string{"from", "to", "type", "category", "sort_by", "sort_order"}
+
+func checkAllowedParams(q url.Values, common []string, extra ...string) error {
 	for param := range q {
-		if !allowedParams[param] {
+		if !slices.Contains(common, param) && !slices.Contains(extra, param) {
 			return fmt.Errorf("unknown parameter '%s'", param)
 		}
 	}
 
+	return nil
+}
+
+func parseItemsFilter(q url.Values, req *dto.GetItemsRequest) error {
 	var err error
 	if fromStr := q.Get("from"); fromStr != "" {
 		req.From, err = parseDate(fromStr)
@@ -76,14 +203,16 @@ func parseGetItemsQuery(r *http.Request, req *dto.GetItemsRequest) error {
 		req.Category = &categoryStr
 	}
 
-	sortByStr := strings.TrimSpace(q.Get("sort_by"))
+	// Sort fields are matched case-insensitively, so they are normalized
+	// once here for the validator, the cursor check and the query.
+	sortByStr := strings.ToLower(strings.TrimSpace(q.Get("sort_by")))
 	if sortByStr != "" {
 		req.SortBy = &sortByStr
 	} else if q.Has("sort_by") {
 		return errors.New("parameter 'sort_by' cannot be empty")
 	}
 
-	sortOrderStr := strings.TrimSpace(q.Get("sort_order"))
+	sortOrderStr := strings.ToLower(strings.TrimSpace(q.Get("sort_order")))
 	if sortOrderStr != "" {
 		req.SortOrder = &sortOrderStr
 	} else if q.Has("sort_order") {
@@ -93,9 +222,139 @@ func parseGetItemsQuery(r *http.Request, req *dto.GetItemsRequest) error {
 	return nil
 }
 
-func parseAnalyticsQuery(r *http.Request, req *dto.AnalyticsRequest) error {
+func parsePagination(q url.Values, req *dto.GetItemsRequest) error {
+	limit := defaultItemsLimit
+	if limitStr := strings.TrimSpace(q.Get("limit")); limitStr != "" {
+		val, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return errors.New("parameter 'limit' must be an integer")
+		}
+		limit = val
+	} else if q.Has("limit") {
+		return errors.New("parameter 'limit' cannot be empty")
+	}
+	req.Limit = &limit
+
+	if offsetStr := strings.TrimSpace(q.Get("offset")); offsetStr != "" {
+		val, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			return errors.New("parameter 'offset' must be an integer")
+		}
+		req.Offset = &val
+	} else if q.Has("offset") {
+		return errors.New("parameter 'offset' cannot be empty")
+	}
+
+	cursorStr := strings.TrimSpace(q.Get("cursor"))
+	if cursorStr == "" {
+		if q.Has("cursor") {
+			return errors.New("parameter 'cursor' cannot be empty")
+		}
+		return nil
+	}
+
+	if req.Offset != nil {
+		return errors.New("parameters 'cursor' and 'offset' cannot be combined")
+	}
+	if req.SortBy != nil && *req.SortBy != "date" {
+		return errors.New("parameter 'cursor' requires sort_by=date")
+	}
+
+	cursor, err := converter.DecodeItemsCursor(cursorStr)
+	if err != nil {
+		return err
+	}
+	req.Cursor = cursor
+
+	return nil
+}
+
+func parseAnalyticsQuery(r *http.Request, req *dto.AnalyticsRequest, defaultLocation *time.Location) error {
 	q := r.URL.Query()
 
+	if err := parseAnalyticsFilter(q, req, defaultLocation); err != nil {
+		return err
+	}
+
+	groupByStr := strings.ToLower(strings.TrimSpace(q.Get("group_by")))
+	if groupByStr != "" {
+		req.GroupBy = &groupByStr
+	} else if q.Has("group_by") {
+		return errors.New("parameter 'group_by' cannot be empty")
+	}
+
+	fillStr := strings.ToLower(strings.TrimSpace(q.Get("fill")))
+	if fillStr != "" {
+		req.Fill = &fillStr
+	} else if q.Has("fill") {
+		return errors.New("parameter 'fill' cannot be empty")
+	}
+
+	compareStr := strings.ToLower(strings.TrimSpace(q.Get("compare")))
+	if compareStr != "" {
+		req.Compare = &compareStr
+	} else if q.Has("compare") {
+		return errors.New("parameter 'compare' cannot be empty")
+	}
+
+	return nil
+}
+
+func parseAnalyticsExportQuery(
+	r *http.Request,
+	req *dto.AnalyticsExportRequest,
+	defaultLocation *time.Location,
+) error {
+	if err := parseAnalyticsQuery(r, &req.AnalyticsRequest, defaultLocation); err != nil {
+		return err
+	}
+
+	q := r.URL.Query()
+	if formatStr := strings.ToLower(strings.TrimSpace(q.Get("format"))); formatStr != "" {
+		req.Format = formatStr
+	} else if q.Has("format") {
+		return errors.New("parameter 'format' cannot be empty")
+	}
+
+	return nil
+}
+
+func parsePivotQuery(r *http.Request, req *dto.PivotRequest, defaultLocation *time.Location) error {
+	q := r.URL.Query()
+
+	var filter dto.AnalyticsRequest
+	if err := parseAnalyticsFilter(q, &filter, defaultLocation); err != nil {
+		return err
+	}
+	req.From, req.To, req.Type, req.Location = filter.From, filter.To, filter.Type, filter.Location
+
+	req.Rows = parseList(q.Get("rows"))
+	req.Columns = parseList(q.Get("columns"))
+	req.Measures = parseList(q.Get("measures"))
+	if len(req.Measures) == 0 {
+		req.Measures = []string{"sum"}
+	}
+
+	seen := make(map[string]bool)
+	for _, dim := range append(append([]string{}, req.Rows...), req.Columns...) {
+		if seen[dim] {
+			return fmt.Errorf("dimension '%s' is used more than once", dim)
+		}
+		seen[dim] = true
+	}
+
+	seen = make(map[string]bool)
+	for _, measure := range req.Measures {
+		if seen[measure] {
+			return fmt.Errorf("measure '%s' is used more than once", measure)
+		}
+		seen[measure] = true
+	}
+
+	return nil
+}
+
+func parseAnalyticsFilter(q url.Values, req *dto.AnalyticsRequest, defaultLocation *time.Location) error {
 	fromStr := q.Get("from")
 	if fromStr == "" {
 		return errors.New("parameter 'from' is required")
@@ -121,11 +380,44 @@ func parseAnalyticsQuery(r *http.Request, req *dto.AnalyticsRequest) error {
 		return errors.New("parameter 'from' cannot be after 'to'")
 	}
 
-	if groupByStr := q.Get("group_by"); groupByStr != "" {
-		req.GroupBy = &groupByStr
+	typeStr := strings.TrimSpace(q.Get("type"))
+	if typeStr != "" {
+		req.Type = &typeStr
+	} else if q.Has("type") {
+		return errors.New("parameter 'type' cannot be empty")
 	}
 
-	return nil
+	req.Location, err = parseLocation(q, defaultLocation)
+
+	return err
+}
+
+func parseLocation(q url.Values, defaultLocation *time.Location) (*time.Location, error) {
+	tzStr := strings.TrimSpace(q.Get("tz"))
+	if tzStr == "" {
+		if q.Has("tz") {
+			return nil, errors.New("parameter 'tz' cannot be empty")
+		}
+		return defaultLocation, nil
+	}
+
+	location, err := time.LoadLocation(tzStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'tz' parameter '%s'", tzStr)
+	}
+
+	return location, nil
+}
+
+func parseList(s string) []string {
+	var list []string
+	for part := range strings.SplitSeq(s, ",") {
+		if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+			list = append(list, part)
+		}
+	}
+
+	return list
 }
 
 func parseDate(s string) (*time.Time, error) {
@@ -140,3 +432,107 @@ func parseDate(s string) (*time.Time, error) {
 	}
 	return &t, nil
 }
+
+// importParams are accepted by every import endpoint.
+var importParams = []string{"dry_run", "allow_duplicates", "tz"}
+
+func parseImportQuery(r *http.Request, req *dto.ImportItemsRequest, defaultLocation *time.Location) error {
+	q := r.URL.Query()
+
+	if err := checkAllowedParams(q, importParams, "mode"); err != nil {
+		return err
+	}
+
+	req.Mode = strings.ToLower(strings.TrimSpace(q.Get("mode")))
+
+	return parseImportOptions(q, &req.ImportOptions, defaultLocation)
+}
+
+func parseStatementImportQuery(
+	r *http.Request,
+	req *dto.ImportStatementRequest,
+	defaultLocation *time.Location,
+) error {
+	q := r.URL.Query()
+
+	if err := checkAllowedParams(q, importParams, "format"); err != nil {
+		return err
+	}
+
+	req.Format = strings.ToLower(strings.TrimSpace(q.Get("format")))
+	if req.Format == "" {
+		return errors.New("parameter 'format' is required")
+	}
+
+	return parseImportOptions(q, &req.ImportOptions, defaultLocation)
+}
+
+func parseWBImportQuery(
+	r *http.Request,
+	req *dto.ImportWBReportRequest,
+	defaultLocation *time.Location,
+) error {
+	q := r.URL.Query()
+
+	if err := checkAllowedParams(q, importParams, "report_id"); err != nil {
+		return err
+	}
+
+	if reportIDStr := strings.TrimSpace(q.Get("report_id")); reportIDStr != "" {
+		reportID, err := strconv.ParseInt(reportIDStr, 10, 64)
+		if err != nil {
+			return errors.New("parameter 'report_id' must be an integer")
+		}
+		req.ReportID = reportID
+	}
+
+	return parseImportOptions(q, &req.ImportOptions, defaultLocation)
+}
+
+// parseImportOptions reads the importParams.
+func parseImportOptions(q url.Values, opts *dto.ImportOptions, defaultLocation *time.Location) error {
+	if dryRunStr := strings.TrimSpace(q.Get("dry_run")); dryRunStr != "" {
+		dryRun, err := strconv.ParseBool(dryRunStr)
+		if err != nil {
+			return errors.New("parameter 'dry_run' must be a boolean")
+		}
+		opts.DryRun = dryRun
+	}
+
+	if allowStr := strings.TrimSpace(q.Get("allow_duplicates")); allowStr != "" {
+		allow, err := strconv.ParseBool(allowStr)
+		if err != nil {
+			return errors.New("parameter 'allow_duplicates' must be a boolean")
+		}
+		opts.AllowDuplicates = allow
+	}
+
+	location, err := parseLocation(q, defaultLocation)
+	if err != nil {
+		return err
+	}
+	opts.Location = location
+
+	return nil
+}
+
+// importFile returns the uploaded file from the "file" form field of a
+// multipart request, or the raw request body otherwise. A multipart body over
+// the size limit yields the *http.MaxBytesError.
+func importFile(r *http.Request) (io.ReadCloser, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, err
+		}
+		return nil, errors.New("form field 'file' is required")
+	}
+
+	return file, nil
+}
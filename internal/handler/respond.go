@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/kstsm/wb-sales-tracker/internal/models"
@@ -27,13 +28,60 @@ func (h *Handler) respondError(w http.ResponseWriter, status int, message string
 	}
 }
 
-func (h *Handler) respondCSV(w http.ResponseWriter, status int, data []byte) {
-	w.Header().Set("Content-Type", "text/csv")
-	w.Header().Set("Content-Disposition", "attachment; filename=items.csv")
-	w.WriteHeader(status)
-	_, err := w.Write(data)
+// streamWriter sends the response headers on the first write and flushes
+// every chunk to the client, so large files are delivered as they are
+// produced. An error that happens before anything was written can still be
+// reported with respondError.
+type streamWriter struct {
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	contentType string
+	filename    string
+	started     bool
+}
+
+func newStreamWriter(w http.ResponseWriter, contentType, filename string) *streamWriter {
+	return &streamWriter{
+		w:           w,
+		rc:          http.NewResponseController(w),
+		contentType: contentType,
+		filename:    filename,
+	}
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if !s.started {
+		s.w.Header().Set("Content-Type", s.contentType)
+		s.w.Header().Set("Content-Disposition", "attachment; filename="+s.filename)
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+
+	n, err := s.w.Write(p)
 	if err != nil {
-		h.log.Errorf("respondJSON: %v", err.Error())
+		return n, err
+	}
+
+	if err = s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return n, err
+	}
+
+	return n, nil
+}
+
+// respondStreamError reports an error from a streamed response. Once data
+// has been sent the status can no longer change, so the connection is aborted
+// to let the client see the download as failed rather than truncated.
+func (h *Handler) respondStreamError(w http.ResponseWriter, r *http.Request, stream *streamWriter, err error) {
+	if r.Context().Err() != nil {
+		h.log.Infof("export cancelled by client: %v", err)
 		return
 	}
+
+	h.log.Errorf("export: %v", err)
+	if stream.started {
+		panic(http.ErrAbortHandler)
+	}
+
+	h.respondError(w, http.StatusInternalServerError, "internal server error")
 }
@@ -5,11 +5,23 @@ import "github.com/go-chi/chi/v5"
 func (h *Handler) registerAPIRoutes(r *chi.Mux) {
 	r.Route("/api", func(r chi.Router) {
 		r.Post("/items", h.createItemHandler)
+		r.Post("/items/batch", h.createItemsBatchHandler)
 		r.Get("/items", h.getItemsHandler)
 		r.Get("/items/{id}", h.getItemByIDHandler)
 		r.Put("/items/{id}", h.updateItemHandler)
 		r.Delete("/items/{id}", h.deleteItemHandler)
 		r.Get("/analytics", h.getAnalyticsHandler)
-		r.Get("/export", h.exportItemCSVHandler)
+		r.Get("/analytics/pivot", h.getPivotHandler)
+		r.Get("/analytics/export", h.exportAnalyticsHandler)
+		r.Get("/export", h.exportItemsHandler)
+		r.Post("/import", h.importItemsCSVHandler)
+		r.Post("/import/statement", h.importStatementHandler)
+		r.Post("/import/wb", h.importWBReportHandler)
+		r.Get("/sync/wb", h.getWBSyncStatusHandler)
+		r.Post("/jobs/import", h.createCSVImportJobHandler)
+		r.Post("/jobs/import/statement", h.createStatementImportJobHandler)
+		r.Post("/jobs/import/wb", h.createWBImportJobHandler)
+		r.Get("/jobs/{id}", h.getJobHandler)
+		r.Delete("/jobs/{id}", h.cancelJobHandler)
 	})
 }
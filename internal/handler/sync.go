@@ -0,0 +1,14 @@
+package handler
+
+import "net/http"
+
+func (h *Handler) getWBSyncStatusHandler(w http.ResponseWriter, r *http.Request) {
+	status, err := h.wbSync.Status(r.Context())
+	if err != nil {
+		h.log.Errorf("getWBSyncStatusHandler: %v", err)
+		h.respondError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, status)
+}
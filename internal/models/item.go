@@ -6,12 +6,17 @@ import (
 	"github.com/google/uuid"
 )
 
+// Item is an income or expense. Items loaded from another system carry the
+// system in Source and their ID there in ExternalID; both are empty for
+// items entered by hand.
 type Item struct {
-	ID        uuid.UUID `json:"id"`
-	Type      string    `json:"type"`
-	Category  string    `json:"category"`
-	Amount    int       `json:"amount"`
-	Date      time.Time `json:"date"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uuid.UUID `json:"id"`
+	Type       string    `json:"type"`
+	Category   string    `json:"category"`
+	Amount     int       `json:"amount"`
+	Date       time.Time `json:"date"`
+	Source     string    `json:"source,omitempty"`
+	ExternalID string    `json:"external_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Phases of a running job. Reading is measured in bytes of the file, the
+// other phases in rows.
+const (
+	JobPhaseReading    = "reading"
+	JobPhaseProcessing = "processing"
+	JobPhaseWriting    = "writing"
+)
+
+// Job is an import run in the background. The uploaded file is kept until
+// the job finishes, so a job interrupted by a restart can run again. Params
+// and Result are the JSON of the import options and response.
+type Job struct {
+	ID         uuid.UUID
+	Type       string
+	Status     string
+	Params     []byte
+	File       []byte
+	TotalBytes int64
+	ReadBytes  int64
+	// Phase is empty until the job reports progress.
+	Phase         string
+	ProcessedRows int
+	TotalRows     int
+	Attempts      int
+	Result        []byte
+	Error         string
+	CreatedAt     time.Time
+	StartedAt     *time.Time
+	FinishedAt    *time.Time
+	UpdatedAt     time.Time
+}
+
+// JobProgress is what a running job reports with its heartbeat.
+type JobProgress struct {
+	ReadBytes     int64
+	Phase         string
+	ProcessedRows int
+	TotalRows     int
+}
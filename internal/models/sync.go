@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SyncState is the progress of syncing one source of the WB statistics API.
+// LastChangeAt is the cursor: the next request asks for records changed
+// since it. LastSkipped counts the records of the last successful run that
+// failed validation; the cursor moves past them, so LastSkippedReason keeps
+// why.
+type SyncState struct {
+	Source            string
+	LastChangeAt      time.Time
+	LastSyncAt        *time.Time
+	LastSuccessAt     *time.Time
+	LastError         string
+	LastCount         int
+	LastSkipped       int
+	LastSkippedReason string
+	UpdatedAt         time.Time
+}
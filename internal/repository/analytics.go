@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -18,12 +19,49 @@ func (r *Repository) GetAnalytics(ctx context.Context, req dto.AnalyticsRequest)
 		return r.getGroupedAnalytics(ctx, whereClause, args, groupBy, req)
 	}
 
+	resp, err := r.getAnalyticsTotals(ctx, whereClause, args)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.From = req.From.Format(time.RFC3339)
+	resp.To = req.To.Format(time.RFC3339)
+	resp.Timezone = r.analyticsLocation(req).String()
+
+	return resp, nil
+}
+
+// GetItemsAnalytics returns the analytics totals for exactly the items matched
+// by an items filter, i.e. the rows GetItems and ExportItems would return.
+func (r *Repository) GetItemsAnalytics(ctx context.Context, req dto.GetItemsRequest) (*dto.AnalyticsResponse, error) {
+	whereClause, args := r.buildItemsWhere(req)
+
+	resp, err := r.getAnalyticsTotals(ctx, whereClause, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.From != nil {
+		resp.From = req.From.Format(time.RFC3339)
+	}
+	if req.To != nil {
+		resp.To = req.To.Format(time.RFC3339)
+	}
+
+	return resp, nil
+}
+
+func (r *Repository) getAnalyticsTotals(ctx context.Context, whereClause string, args []any) (
+	*dto.AnalyticsResponse,
+	error,
+) {
 	var sum sql.NullFloat64
 	var avg, median, percentile90 sql.NullFloat64
-	var count int
+	var count, incomeCount, expenseCount int
+	var income, expense int64
 
 	err := r.conn.QueryRow(ctx, fmt.Sprintf(queries.AnalyticsQuery, whereClause), args...).
-		Scan(&sum, &avg, &count, &median, &percentile90)
+		Scan(&sum, &avg, &count, &median, &percentile90, &income, &expense, &incomeCount, &expenseCount)
 	if err != nil {
 		return nil, fmt.Errorf("QueryRow-GetAnalytics: %w", err)
 	}
@@ -53,17 +91,20 @@ func (r *Repository) GetAnalytics(ctx context.Context, req dto.AnalyticsRequest)
 		percentile90Value = &val
 	}
 
-	fromStr := req.From.Format(time.RFC3339)
-	toStr := req.To.Format(time.RFC3339)
+	incomeValue := float64(income) / kopeksPerRuble
+	expenseValue := float64(expense) / kopeksPerRuble
 
 	return &dto.AnalyticsResponse{
-		From:         fromStr,
-		To:           toStr,
 		Sum:          sumValue,
 		Avg:          avgValue,
 		Count:        count,
 		Median:       medianValue,
 		Percentile90: percentile90Value,
+		Income:       incomeValue,
+		Expense:      expenseValue,
+		Net:          incomeValue - expenseValue,
+		IncomeCount:  incomeCount,
+		ExpenseCount: expenseCount,
 	}, nil
 }
 
@@ -73,29 +114,41 @@ func (r *Repository) getGroupedAnalytics(ctx context.Context,
 	groupBy string,
 	req dto.AnalyticsRequest,
 ) (*dto.AnalyticsResponse, error) {
-	query, err := r.getGroupedQuery(groupBy, whereClause)
+	query, queryArgs, err := r.getGroupedQuery(groupBy, whereClause, args, req)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := r.conn.Query(ctx, query, args...)
+	rows, err := r.conn.Query(ctx, query, queryArgs...)
 	if err != nil {
 		return nil, fmt.Errorf("Query-GetGroupedAnalytics: %w", err)
 	}
 	defer rows.Close()
 
 	var grouped []dto.GroupedAnalytics
-	var totalSum float64
-	var totalCount int
+	var totalSum, totalIncome, totalExpense float64
+	var totalCount, totalIncomeCount, totalExpenseCount int
 
 	const kopeksPerRuble = 100.0
 
 	for rows.Next() {
 		var groupKey any
 		var sum, avg, median, percentile90 sql.NullFloat64
-		var count int
-
-		if scanErr := rows.Scan(&groupKey, &sum, &avg, &count, &median, &percentile90); scanErr != nil {
+		var count, incomeCount, expenseCount int
+		var income, expense int64
+
+		if scanErr := rows.Scan(
+			&groupKey,
+			&sum,
+			&avg,
+			&count,
+			&median,
+			&percentile90,
+			&income,
+			&expense,
+			&incomeCount,
+			&expenseCount,
+		); scanErr != nil {
 			return nil, fmt.Errorf("Scan-GetGroupedAnalytics: %w", scanErr)
 		}
 
@@ -124,7 +177,15 @@ func (r *Repository) getGroupedAnalytics(ctx context.Context,
 			percentile90Value = &val
 		}
 
+		incomeValue := float64(income) / kopeksPerRuble
+		expenseValue := float64(expense) / kopeksPerRuble
+		netValue := incomeValue - expenseValue
+
 		totalCount += count
+		totalIncome += incomeValue
+		totalExpense += expenseValue
+		totalIncomeCount += incomeCount
+		totalExpenseCount += expenseCount
 
 		grouped = append(grouped, dto.GroupedAnalytics{
 			Group:        converter.FormatGroupKey(groupKey, groupBy),
@@ -133,6 +194,11 @@ func (r *Repository) getGroupedAnalytics(ctx context.Context,
 			Count:        count,
 			Median:       medianValue,
 			Percentile90: percentile90Value,
+			Income:       &incomeValue,
+			Expense:      &expenseValue,
+			Net:          &netValue,
+			IncomeCount:  incomeCount,
+			ExpenseCount: expenseCount,
 		})
 	}
 
@@ -150,26 +216,66 @@ func (r *Repository) getGroupedAnalytics(ctx context.Context,
 	toStr := req.To.Format(time.RFC3339)
 
 	return &dto.AnalyticsResponse{
-		From:    fromStr,
-		To:      toStr,
-		Sum:     totalSum,
-		Avg:     totalAvg,
-		Count:   totalCount,
-		Grouped: grouped,
+		From:         fromStr,
+		To:           toStr,
+		Timezone:     r.analyticsLocation(req).String(),
+		Sum:          totalSum,
+		Avg:          totalAvg,
+		Count:        totalCount,
+		Income:       totalIncome,
+		Expense:      totalExpense,
+		Net:          totalIncome - totalExpense,
+		IncomeCount:  totalIncomeCount,
+		ExpenseCount: totalExpenseCount,
+		Grouped:      grouped,
 	}, nil
 }
 
-func (r *Repository) getGroupedQuery(groupBy, whereClause string) (string, error) {
+func (r *Repository) getGroupedQuery(
+	groupBy, whereClause string,
+	args []any,
+	req dto.AnalyticsRequest,
+) (string, []any, error) {
+	groupKey, queryArgs, err := r.groupKeyExpr(groupBy, args, req)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return fmt.Sprintf(queries.AnalyticsGroupedQuery, groupKey, whereClause), queryArgs, nil
+}
+
+// groupKeyExpr returns the SQL expression that buckets rows by groupBy in the
+// request timezone. The timezone name and, for custom intervals, the start of
+// the requested range are appended to a copy of args.
+func (r *Repository) groupKeyExpr(groupBy string, args []any, req dto.AnalyticsRequest) (string, []any, error) {
+	switch groupBy {
+	case "category", "type":
+		return groupBy, args, nil
+	}
+
+	tzParam := len(args) + 1
+	localDate := fmt.Sprintf("(date AT TIME ZONE $%d::text)", tzParam)
+	args = append(slices.Clone(args), r.analyticsLocation(req).String())
+
 	switch groupBy {
 	case "day":
-		return fmt.Sprintf(queries.AnalyticsGroupedByDayQuery, whereClause), nil
-	case "week":
-		return fmt.Sprintf(queries.AnalyticsGroupedByWeekQuery, whereClause), nil
-	case "category":
-		return fmt.Sprintf(queries.AnalyticsGroupedByCategoryQuery, whereClause), nil
-	default:
-		return "", fmt.Errorf("unsupported group_by value: %s", groupBy)
+		return fmt.Sprintf("DATE%s", localDate), args, nil
+	case "week", "month", "quarter", "year":
+		return fmt.Sprintf("DATE_TRUNC('%s', %s)", groupBy, localDate), args, nil
+	case "weekday":
+		return fmt.Sprintf("EXTRACT(ISODOW FROM %s)::int", localDate), args, nil
+	case "hour":
+		return fmt.Sprintf("EXTRACT(HOUR FROM %s)::int", localDate), args, nil
 	}
+
+	interval, ok := converter.ParseGroupInterval(groupBy)
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported group_by value: %s", groupBy)
+	}
+
+	expr := fmt.Sprintf("DATE_BIN(INTERVAL '%s', %s, $%d::timestamptz AT TIME ZONE $%d::text)",
+		interval, localDate, len(args)+1, tzParam)
+	return expr, append(args, r.analyticsRangeStart(req)), nil
 }
 
 func (r *Repository) buildAnalyticsWhere(req dto.AnalyticsRequest) (string, []any) {
@@ -182,19 +288,22 @@ func (r *Repository) buildAnalyticsWhere(req dto.AnalyticsRequest) (string, []an
 	}
 
 	if req.From != nil {
-		fromStartOfDay := time.Date(req.From.Year(),
-			req.From.Month(),
-			req.From.Day(), 0, 0, 0, 0,
-			req.From.Location())
-		add("date >= $%d", fromStartOfDay)
+		add("date >= $%d", r.analyticsRangeStart(req))
 	}
 	if req.To != nil {
-		toEndOfDay := time.Date(req.To.Year(),
-			req.To.Month(),
-			req.To.Day(), 23, 59, 59, 999999999,
-			req.To.Location())
+		to := req.To.In(r.analyticsLocation(req))
+		toEndOfDay := time.Date(to.Year(),
+			to.Month(),
+			to.Day(), 23, 59, 59, 999999999,
+			to.Location())
 		add("date <= $%d", toEndOfDay)
 	}
+	if req.Type != nil {
+		add("type = $%d", *req.Type)
+	}
+	if req.Category != nil {
+		add("category = $%d", *req.Category)
+	}
 
 	if len(cond) == 0 {
 		return "", args
@@ -203,17 +312,30 @@ func (r *Repository) buildAnalyticsWhere(req dto.AnalyticsRequest) (string, []an
 	return " WHERE " + strings.Join(cond, " AND "), args
 }
 
+func (r *Repository) analyticsRangeStart(req dto.AnalyticsRequest) time.Time {
+	if req.From == nil {
+		return time.Time{}
+	}
+
+	from := req.From.In(r.analyticsLocation(req))
+	return time.Date(from.Year(),
+		from.Month(),
+		from.Day(), 0, 0, 0, 0,
+		from.Location())
+}
+
+func (r *Repository) analyticsLocation(req dto.AnalyticsRequest) *time.Location {
+	if req.Location == nil {
+		return time.UTC
+	}
+
+	return req.Location
+}
+
 func (r *Repository) getAnalyticsGroupBy(req dto.AnalyticsRequest) string {
 	if req.GroupBy == nil {
 		return ""
 	}
-	allowed := map[string]string{
-		"day":      "day",
-		"week":     "week",
-		"category": "category",
-	}
-	if val, ok := allowed[strings.ToLower(*req.GroupBy)]; ok {
-		return val
-	}
-	return ""
+
+	return strings.ToLower(strings.TrimSpace(*req.GroupBy))
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -21,6 +23,8 @@ func (r *Repository) CreateItem(ctx context.Context, item models.Item) error {
 		item.Amount,
 		item.Date,
 		item.Category,
+		item.Source,
+		item.ExternalID,
 		item.CreatedAt,
 		item.UpdatedAt,
 	)
@@ -31,6 +35,147 @@ func (r *Repository) CreateItem(ctx context.Context, item models.Item) error {
 	return nil
 }
 
+// UpsertItemByExternalID inserts item, or overwrites the item stored under
+// the same source and external id. It returns the stored item and whether it
+// was inserted.
+func (r *Repository) UpsertItemByExternalID(ctx context.Context, item models.Item) (*models.Item, bool, error) {
+	var (
+		stored   models.Item
+		inserted bool
+	)
+
+	err := r.conn.QueryRow(ctx, queries.UpsertItemByExternalIDQuery,
+		item.ID,
+		item.Type,
+		item.Amount,
+		item.Date,
+		item.Category,
+		item.Source,
+		item.ExternalID,
+		item.CreatedAt,
+		item.UpdatedAt,
+	).Scan(
+		&stored.ID,
+		&stored.Type,
+		&stored.Amount,
+		&stored.Date,
+		&stored.Category,
+		&stored.Source,
+		&stored.ExternalID,
+		&stored.CreatedAt,
+		&stored.UpdatedAt,
+		&inserted,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("QueryRow-UpsertItemByExternalID: %w", err)
+	}
+
+	return &stored, inserted, nil
+}
+
+// UpsertItems writes items in one transaction, inserting new ids and
+// overwriting existing ones. An item with an external id overwrites the item
+// stored under the same source and external id, whatever its id. Items are
+// updated in place with the stored id and timestamps. It returns the number
+// of inserted and updated rows.
+func (r *Repository) UpsertItems(ctx context.Context, items []models.Item) (int, int, error) {
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Begin-UpsertItems: %w", err)
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(ctx); rollbackErr != nil && !errors.Is(rollbackErr, pgx.ErrTxClosed) {
+			r.log.Errorf("Rollback-UpsertItems: %v", rollbackErr)
+		}
+	}()
+
+	if _, err = tx.Exec(ctx, queries.CreateImportTableQuery); err != nil {
+		return 0, 0, fmt.Errorf("Exec-UpsertItems: %w", err)
+	}
+
+	if _, err = tx.CopyFrom(ctx, pgx.Identifier{"items_import"}, itemColumns(), itemRows(items)); err != nil {
+		return 0, 0, fmt.Errorf("CopyFrom-UpsertItems: %w", err)
+	}
+
+	if err = resolveExternalIDs(ctx, tx, items); err != nil {
+		return 0, 0, err
+	}
+
+	byID := make(map[uuid.UUID]int, len(items))
+	for i := range items {
+		byID[items[i].ID] = i
+	}
+
+	rows, err := tx.Query(ctx, queries.UpsertImportedItemsQuery)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Query-UpsertItems: %w", err)
+	}
+
+	var inserted, updated int
+	for rows.Next() {
+		var (
+			id                   uuid.UUID
+			createdAt, updatedAt time.Time
+			isInsert             bool
+		)
+		if err = rows.Scan(&id, &createdAt, &updatedAt, &isInsert); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("Scan-UpsertItems: %w", err)
+		}
+		if i, ok := byID[id]; ok {
+			items[i].CreatedAt, items[i].UpdatedAt = createdAt, updatedAt
+		}
+		if isInsert {
+			inserted++
+		} else {
+			updated++
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("Err-UpsertItems: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("Commit-UpsertItems: %w", err)
+	}
+
+	return inserted, updated, nil
+}
+
+// resolveExternalIDs gives the imported items, and items, the id of the
+// stored item with the same source and external id.
+func resolveExternalIDs(ctx context.Context, tx pgx.Tx, items []models.Item) error {
+	rows, err := tx.Query(ctx, queries.ResolveImportedExternalIDsQuery)
+	if err != nil {
+		return fmt.Errorf("Query-ResolveExternalIDs: %w", err)
+	}
+	defer rows.Close()
+
+	resolved := make(map[[2]string]uuid.UUID)
+	for rows.Next() {
+		var (
+			source, externalID string
+			id                 uuid.UUID
+		)
+		if err = rows.Scan(&source, &externalID, &id); err != nil {
+			return fmt.Errorf("Scan-ResolveExternalIDs: %w", err)
+		}
+		resolved[[2]string{source, externalID}] = id
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("Err-ResolveExternalIDs: %w", err)
+	}
+
+	for i := range items {
+		if id, ok := resolved[[2]string{items[i].Source, items[i].ExternalID}]; ok && items[i].ExternalID != "" {
+			items[i].ID = id
+		}
+	}
+
+	return nil
+}
+
 func (r *Repository) GetItemByID(ctx context.Context, id uuid.UUID) (*models.Item, error) {
 	var item models.Item
 
@@ -40,6 +185,8 @@ func (r *Repository) GetItemByID(ctx context.Context, id uuid.UUID) (*models.Ite
 		&item.Amount,
 		&item.Date,
 		&item.Category,
+		&item.Source,
+		&item.ExternalID,
 		&item.CreatedAt,
 		&item.UpdatedAt,
 	)
@@ -53,6 +200,91 @@ func (r *Repository) GetItemByID(ctx context.Context, id uuid.UUID) (*models.Ite
 	return &item, nil
 }
 
+// ExistingItemIDs reports which of ids are already stored.
+func (r *Repository) ExistingItemIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	existing := make(map[uuid.UUID]bool)
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	rows, err := r.conn.Query(ctx, queries.ExistingItemIDsQuery, ids)
+	if err != nil {
+		return nil, fmt.Errorf("Query-ExistingItemIDs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("Scan-ExistingItemIDs: %w", err)
+		}
+		existing[id] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Err-ExistingItemIDs: %w", err)
+	}
+
+	return existing, nil
+}
+
+// FindSimilarItems returns, by index in items, the ids of stored items that
+// may be duplicates of them: same type, amount and category, dated within
+// window and not from the same source.
+func (r *Repository) FindSimilarItems(
+	ctx context.Context,
+	items []models.Item,
+	window time.Duration,
+) (map[int][]uuid.UUID, error) {
+	similar := make(map[int][]uuid.UUID)
+	if len(items) == 0 {
+		return similar, nil
+	}
+
+	var (
+		indexes    = make([]int, len(items))
+		ids        = make([]uuid.UUID, len(items))
+		types      = make([]string, len(items))
+		amounts    = make([]int, len(items))
+		categories = make([]string, len(items))
+		dates      = make([]time.Time, len(items))
+		sources    = make([]string, len(items))
+	)
+	for i, item := range items {
+		indexes[i] = i
+		ids[i] = item.ID
+		types[i] = item.Type
+		amounts[i] = item.Amount
+		categories[i] = item.Category
+		dates[i] = item.Date
+		sources[i] = item.Source
+	}
+
+	rows, err := r.conn.Query(ctx, queries.FindSimilarItemsQuery,
+		indexes, ids, types, amounts, categories, dates, sources, window.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("Query-FindSimilarItems: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			idx int
+			id  uuid.UUID
+		)
+		if err = rows.Scan(&idx, &id); err != nil {
+			return nil, fmt.Errorf("Scan-FindSimilarItems: %w", err)
+		}
+		similar[idx] = append(similar[idx], id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Err-FindSimilarItems: %w", err)
+	}
+
+	return similar, nil
+}
+
 func (r *Repository) GetItems(ctx context.Context, req dto.GetItemsRequest) ([]*models.Item, int, error) {
 	whereClause, args := r.buildItemsWhere(req)
 	orderClause := r.buildItemsOrder(req)
@@ -62,7 +294,10 @@ func (r *Repository) GetItems(ctx context.Context, req dto.GetItemsRequest) ([]*
 		return nil, 0, fmt.Errorf("QueryRow-GetItems: %w", err)
 	}
 
-	rows, err := r.conn.Query(ctx, queries.BaseSelectQuery+whereClause+orderClause, args...)
+	pageWhere, pageArgs := r.buildItemsCursor(req, whereClause, args)
+	pageClause := r.buildItemsPage(req)
+
+	rows, err := r.conn.Query(ctx, queries.BaseSelectQuery+pageWhere+orderClause+pageClause, pageArgs...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("Query-GetItems: %w", err)
 	}
@@ -77,6 +312,8 @@ func (r *Repository) GetItems(ctx context.Context, req dto.GetItemsRequest) ([]*
 			&item.Amount,
 			&item.Date,
 			&item.Category,
+			&item.Source,
+			&item.ExternalID,
 			&item.CreatedAt,
 			&item.UpdatedAt,
 		); err != nil {
@@ -115,6 +352,8 @@ func (r *Repository) UpdateItem(ctx context.Context, id uuid.UUID, req dto.Updat
 		&item.Amount,
 		&item.Date,
 		&item.Category,
+		&item.Source,
+		&item.ExternalID,
 		&item.CreatedAt,
 		&item.UpdatedAt,
 	)
@@ -141,17 +380,19 @@ func (r *Repository) DeleteItem(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func (r *Repository) GetItemsForExport(ctx context.Context, req dto.GetItemsRequest) ([]*models.Item, error) {
+// ExportItems passes every item matching req to fn in export order as rows
+// are read from the database, without collecting them in memory. Iteration
+// stops at the first error returned by fn or when ctx is cancelled.
+func (r *Repository) ExportItems(ctx context.Context, req dto.GetItemsRequest, fn func(*models.Item) error) error {
 	whereClause, args := r.buildItemsWhere(req)
 	orderClause := r.buildItemsOrder(req)
 
 	rows, err := r.conn.Query(ctx, queries.BaseSelectQuery+whereClause+orderClause, args...)
 	if err != nil {
-		return nil, fmt.Errorf("Query-GetItemsForExport: %w", err)
+		return fmt.Errorf("Query-ExportItems: %w", err)
 	}
 	defer rows.Close()
 
-	var items []*models.Item
 	for rows.Next() {
 		var item models.Item
 		if err = rows.Scan(
@@ -160,19 +401,24 @@ func (r *Repository) GetItemsForExport(ctx context.Context, req dto.GetItemsRequ
 			&item.Amount,
 			&item.Date,
 			&item.Category,
+			&item.Source,
+			&item.ExternalID,
 			&item.CreatedAt,
 			&item.UpdatedAt,
 		); err != nil {
-			return nil, fmt.Errorf("Scan-GetItemsForExport: %w", err)
+			return fmt.Errorf("Scan-ExportItems: %w", err)
+		}
+
+		if err = fn(&item); err != nil {
+			return err
 		}
-		items = append(items, &item)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("Err-GetItemsForExport: %w", err)
+		return fmt.Errorf("Err-ExportItems: %w", err)
 	}
 
-	return items, nil
+	return nil
 }
 
 func (r *Repository) buildItemsWhere(req dto.GetItemsRequest) (string, []any) {
@@ -204,6 +450,42 @@ func (r *Repository) buildItemsWhere(req dto.GetItemsRequest) (string, []any) {
 	return " WHERE " + strings.Join(cond, " AND "), args
 }
 
+func (r *Repository) buildItemsCursor(req dto.GetItemsRequest, whereClause string, args []any) (string, []any) {
+	if req.Cursor == nil {
+		return whereClause, args
+	}
+
+	op := "<"
+	if !r.isItemsOrderDesc(req) {
+		op = ">"
+	}
+
+	cond := fmt.Sprintf("(date, id) %s ($%d, $%d)", op, len(args)+1, len(args)+2)
+	pageArgs := append(slices.Clone(args), req.Cursor.Date, req.Cursor.ID)
+
+	if whereClause == "" {
+		return " WHERE " + cond, pageArgs
+	}
+
+	return whereClause + " AND " + cond, pageArgs
+}
+
+func (r *Repository) buildItemsPage(req dto.GetItemsRequest) string {
+	var clause string
+	if req.Limit != nil {
+		clause += fmt.Sprintf(" LIMIT %d", *req.Limit)
+	}
+	if req.Offset != nil {
+		clause += fmt.Sprintf(" OFFSET %d", *req.Offset)
+	}
+
+	return clause
+}
+
+func (r *Repository) isItemsOrderDesc(req dto.GetItemsRequest) bool {
+	return req.SortOrder == nil || !strings.EqualFold(*req.SortOrder, "asc")
+}
+
 func (r *Repository) buildItemsOrder(req dto.GetItemsRequest) string {
 	sortBy := "date"
 	sortOrder := "desc"
@@ -225,5 +507,35 @@ func (r *Repository) buildItemsOrder(req dto.GetItemsRequest) string {
 		}
 	}
 
-	return fmt.Sprintf(" ORDER BY %s %s", sortBy, sortOrder)
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", sortBy, sortOrder, sortOrder)
+}
+
+func itemColumns() []string {
+	return []string{"id", "type", "amount", "date", "category", "source", "external_id", "created_at", "updated_at"}
+}
+
+func itemRows(items []models.Item) pgx.CopyFromSource {
+	return pgx.CopyFromSlice(len(items), func(i int) ([]any, error) {
+		return []any{
+			items[i].ID,
+			items[i].Type,
+			items[i].Amount,
+			items[i].Date,
+			items[i].Category,
+			nullString(items[i].Source),
+			nullString(items[i].ExternalID),
+			items[i].CreatedAt,
+			items[i].UpdatedAt,
+		}, nil
+	})
+}
+
+// nullString stores an empty string as NULL, as the unique source and
+// external id pair requires for items without one.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+
+	return s
 }
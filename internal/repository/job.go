@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+	"github.com/kstsm/wb-sales-tracker/internal/repository/queries"
+)
+
+func (r *Repository) CreateJob(ctx context.Context, job *models.Job) error {
+	err := r.conn.QueryRow(ctx, queries.CreateJobQuery,
+		job.ID,
+		job.Type,
+		job.Params,
+		job.File,
+		job.TotalBytes,
+	).Scan(&job.Status, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("QueryRow-CreateJob: %w", err)
+	}
+
+	return nil
+}
+
+// GetJob returns the job without its file.
+func (r *Repository) GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	job, err := scanJob(r.conn.QueryRow(ctx, queries.GetJobQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperrors.ErrJobNotFound
+		}
+		return nil, fmt.Errorf("QueryRow-GetJob: %w", err)
+	}
+
+	return job, nil
+}
+
+// ClaimJob marks the oldest queued job as running and returns it with its
+// file, or nil if the queue is empty.
+func (r *Repository) ClaimJob(ctx context.Context) (*models.Job, error) {
+	var job models.Job
+
+	err := r.conn.QueryRow(ctx, queries.ClaimJobQuery).Scan(
+		&job.ID,
+		&job.Type,
+		&job.Status,
+		&job.Params,
+		&job.File,
+		&job.TotalBytes,
+		&job.ReadBytes,
+		&job.Phase,
+		&job.ProcessedRows,
+		&job.TotalRows,
+		&job.Attempts,
+		&job.Result,
+		&job.Error,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("QueryRow-ClaimJob: %w", err)
+	}
+
+	return &job, nil
+}
+
+// UpdateJobProgress stores the progress of a running job and reports whether
+// it is still running, that is, was not cancelled.
+func (r *Repository) UpdateJobProgress(ctx context.Context, id uuid.UUID, progress models.JobProgress) (bool, error) {
+	tag, err := r.conn.Exec(ctx, queries.UpdateJobProgressQuery,
+		id,
+		progress.ReadBytes,
+		progress.Phase,
+		progress.ProcessedRows,
+		progress.TotalRows,
+	)
+	if err != nil {
+		return false, fmt.Errorf("Exec-UpdateJobProgress: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// FinishJob records the outcome of a running job and drops its file. A job
+// cancelled in the meantime keeps its status.
+func (r *Repository) FinishJob(ctx context.Context, id uuid.UUID, status string, result []byte, errMsg string) error {
+	_, err := r.conn.Exec(ctx, queries.FinishJobQuery, id, status, result, errMsg)
+	if err != nil {
+		return fmt.Errorf("Exec-FinishJob: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) RequeueJob(ctx context.Context, id uuid.UUID) error {
+	_, err := r.conn.Exec(ctx, queries.RequeueJobQuery, id)
+	if err != nil {
+		return fmt.Errorf("Exec-RequeueJob: %w", err)
+	}
+
+	return nil
+}
+
+// RequeueStaleJobs returns running jobs not updated for staleAfter to the
+// queue, or fails them once they used maxAttempts. It returns the number of
+// recovered jobs.
+func (r *Repository) RequeueStaleJobs(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int, error) {
+	tag, err := r.conn.Exec(ctx, queries.RequeueStaleJobsQuery, staleAfter.Seconds(), maxAttempts)
+	if err != nil {
+		return 0, fmt.Errorf("Exec-RequeueStaleJobs: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// CancelJob cancels a queued or running job. It returns ErrJobFinished for
+// jobs that have already finished.
+func (r *Repository) CancelJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	job, err := scanJob(r.conn.QueryRow(ctx, queries.CancelJobQuery, id))
+	if err == nil {
+		return job, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("QueryRow-CancelJob: %w", err)
+	}
+
+	if _, err = r.GetJob(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return nil, apperrors.ErrJobFinished
+}
+
+func scanJob(row pgx.Row) (*models.Job, error) {
+	var job models.Job
+
+	err := row.Scan(
+		&job.ID,
+		&job.Type,
+		&job.Status,
+		&job.Params,
+		&job.TotalBytes,
+		&job.ReadBytes,
+		&job.Phase,
+		&job.ProcessedRows,
+		&job.TotalRows,
+		&job.Attempts,
+		&job.Result,
+		&job.Error,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/repository/queries"
+)
+
+const pivotKeySeparator = "\x1f"
+
+func (r *Repository) GetPivot(ctx context.Context, req dto.PivotRequest) (*dto.PivotResponse, error) {
+	filter := dto.AnalyticsRequest{From: req.From, To: req.To, Type: req.Type, Location: req.Location}
+	whereClause, args := r.buildAnalyticsWhere(filter)
+
+	dims := append(append([]string{}, req.Rows...), req.Columns...)
+	dimExprs := make([]string, len(dims))
+	for i, dim := range dims {
+		expr, dimArgs, err := r.groupKeyExpr(dim, args, filter)
+		if err != nil {
+			return nil, err
+		}
+		dimExprs[i], args = expr, dimArgs
+	}
+
+	query, err := r.buildPivotQuery(dimExprs, len(req.Rows), req.Measures, whereClause)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Query-GetPivot: %w", err)
+	}
+	defer rows.Close()
+
+	resp := newPivotResponse(req)
+	resp.Timezone = r.analyticsLocation(filter).String()
+	cells := make(map[string]dto.PivotValues)
+
+	rowMask := int32(1<<len(dims)) - int32(1<<len(req.Columns))
+	colMask := int32(1<<len(req.Columns)) - 1
+
+	for rows.Next() {
+		values, valuesErr := rows.Values()
+		if valuesErr != nil {
+			return nil, fmt.Errorf("Values-GetPivot: %w", valuesErr)
+		}
+
+		key := make([]string, len(dims))
+		for i, dim := range dims {
+			if values[i] != nil {
+				key[i] = converter.FormatGroupKey(values[i], dim)
+			}
+		}
+		rowKey, colKey := key[:len(req.Rows)], key[len(req.Rows):]
+
+		mask, ok := values[len(dims)].(int32)
+		if !ok {
+			return nil, fmt.Errorf("GetPivot: unexpected grouping mask %T", values[len(dims)])
+		}
+		measures := r.pivotValues(req.Measures, values[len(dims)+1:])
+
+		switch {
+		case mask&rowMask == 0 && mask&colMask == 0:
+			cells[pivotCellKey(rowKey, colKey)] = measures
+		case mask&rowMask == 0:
+			resp.RowKeys = append(resp.RowKeys, rowKey)
+			resp.RowTotals = append(resp.RowTotals, measures)
+		case mask&colMask == 0:
+			resp.ColumnKeys = append(resp.ColumnKeys, colKey)
+			resp.ColumnTotals = append(resp.ColumnTotals, measures)
+		default:
+			resp.GrandTotal = measures
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err-GetPivot: %w", err)
+	}
+
+	resp.Cells = make([][]dto.PivotValues, len(resp.RowKeys))
+	for i, rowKey := range resp.RowKeys {
+		resp.Cells[i] = make([]dto.PivotValues, len(resp.ColumnKeys))
+		for j, colKey := range resp.ColumnKeys {
+			resp.Cells[i][j] = cells[pivotCellKey(rowKey, colKey)]
+		}
+	}
+
+	return resp, nil
+}
+
+// buildPivotQuery aggregates every (rows, columns), rows, columns and grand
+// total combination in a single pass using GROUPING SETS. The GROUPING() mask
+// tells which of these sets a result row belongs to.
+func (r *Repository) buildPivotQuery(dimExprs []string, rowCount int, measures []string, whereClause string) (
+	string,
+	error,
+) {
+	selectList := make([]string, 0, len(dimExprs)+len(measures)+1)
+	orderBy := make([]string, 0, len(dimExprs))
+	for i, expr := range dimExprs {
+		selectList = append(selectList, fmt.Sprintf("%s AS d%d", expr, i))
+		orderBy = append(orderBy, strconv.Itoa(i+1))
+	}
+	selectList = append(selectList, fmt.Sprintf("GROUPING(%s) AS grouping_mask", strings.Join(dimExprs, ", ")))
+
+	for i, measure := range measures {
+		expr, err := r.pivotMeasureExpr(measure)
+		if err != nil {
+			return "", err
+		}
+		selectList = append(selectList, fmt.Sprintf("(%s)::float8 AS m%d", expr, i))
+	}
+
+	groupingSets := []string{
+		"(" + strings.Join(dimExprs, ", ") + ")",
+		"(" + strings.Join(dimExprs[:rowCount], ", ") + ")",
+		"(" + strings.Join(dimExprs[rowCount:], ", ") + ")",
+		"()",
+	}
+
+	return fmt.Sprintf(queries.AnalyticsPivotQuery,
+		strings.Join(selectList, ",\n\t\t\t"),
+		whereClause,
+		strings.Join(groupingSets, ", "),
+		strings.Join(orderBy, ", "),
+	), nil
+}
+
+func (r *Repository) pivotMeasureExpr(measure string) (string, error) {
+	switch measure {
+	case "sum":
+		return "COALESCE(SUM(amount), 0)", nil
+	case "count":
+		return "COUNT(*)", nil
+	case "avg":
+		return "AVG(amount)", nil
+	case "median":
+		return "PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY amount)", nil
+	case "p90":
+		return "PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY amount)", nil
+	case "income":
+		return "COALESCE(SUM(amount) FILTER (WHERE type = 'income'), 0)", nil
+	case "expense":
+		return "COALESCE(SUM(amount) FILTER (WHERE type = 'expense'), 0)", nil
+	case "net":
+		return "COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE -amount END), 0)", nil
+	default:
+		return "", fmt.Errorf("unsupported measure: %s", measure)
+	}
+}
+
+func (r *Repository) pivotValues(measures []string, values []any) dto.PivotValues {
+	const kopeksPerRuble = 100.0
+
+	result := make(dto.PivotValues, len(measures))
+	for i, measure := range measures {
+		v, ok := values[i].(float64)
+		if !ok {
+			result[measure] = nil
+			continue
+		}
+		if measure != "count" {
+			v /= kopeksPerRuble
+		}
+		result[measure] = &v
+	}
+
+	return result
+}
+
+func newPivotResponse(req dto.PivotRequest) *dto.PivotResponse {
+	resp := &dto.PivotResponse{
+		Rows:         req.Rows,
+		Columns:      req.Columns,
+		Measures:     req.Measures,
+		RowKeys:      [][]string{},
+		ColumnKeys:   [][]string{},
+		RowTotals:    []dto.PivotValues{},
+		ColumnTotals: []dto.PivotValues{},
+	}
+	if req.From != nil {
+		resp.From = req.From.Format(time.RFC3339)
+	}
+	if req.To != nil {
+		resp.To = req.To.Format(time.RFC3339)
+	}
+
+	return resp
+}
+
+func pivotCellKey(rowKey, colKey []string) string {
+	return strings.Join(rowKey, pivotKeySeparator) + pivotKeySeparator + strings.Join(colKey, pivotKeySeparator)
+}
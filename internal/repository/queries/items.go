@@ -7,9 +7,73 @@ const (
 		                   amount,
 		                   date,
 		                   category,
+		                   source,
+		                   external_id,
 		                   created_at,
 		                   updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9)
+`
+
+	UpsertItemByExternalIDQuery = `
+		INSERT INTO items (id,
+		                   type,
+		                   amount,
+		                   date,
+		                   category,
+		                   source,
+		                   external_id,
+		                   created_at,
+		                   updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (source, external_id) DO UPDATE
+		SET type = EXCLUDED.type,
+		    amount = EXCLUDED.amount,
+		    date = EXCLUDED.date,
+		    category = EXCLUDED.category,
+		    updated_at = NOW()
+		RETURNING id, type, amount, date, category, source, external_id, created_at, updated_at, (xmax = 0) AS inserted
+`
+
+	CreateImportTableQuery = `
+		CREATE TEMP TABLE items_import
+		(LIKE items INCLUDING DEFAULTS)
+		ON COMMIT DROP
+`
+
+	// ResolveImportedExternalIDsQuery gives imported items the id of the
+	// stored item with the same source and external id, so that the upsert
+	// by id updates it.
+	ResolveImportedExternalIDsQuery = `
+		UPDATE items_import AS imported
+		SET id = items.id
+		FROM items
+		WHERE imported.external_id IS NOT NULL
+		  AND items.source = imported.source
+		  AND items.external_id = imported.external_id
+		RETURNING imported.source, imported.external_id, imported.id
+`
+
+	UpsertImportedItemsQuery = `
+		INSERT INTO items (id,
+		                   type,
+		                   amount,
+		                   date,
+		                   category,
+		                   source,
+		                   external_id,
+		                   created_at,
+		                   updated_at)
+		SELECT id, type, amount, date, category, source, external_id, created_at, updated_at
+		FROM items_import
+		ON CONFLICT (id) DO UPDATE
+		SET type = EXCLUDED.type,
+		    amount = EXCLUDED.amount,
+		    date = EXCLUDED.date,
+		    category = EXCLUDED.category,
+		    source = COALESCE(EXCLUDED.source, items.source),
+		    external_id = COALESCE(EXCLUDED.external_id, items.external_id),
+		    updated_at = NOW()
+		RETURNING id, created_at, updated_at, (xmax = 0) AS inserted
 `
 
 	GetItemByIDQuery = `
@@ -18,12 +82,38 @@ const (
 		       amount,
 		       date,
 		       category,
+		       COALESCE(source, ''),
+		       COALESCE(external_id, ''),
 		       created_at,
 		       updated_at
 		FROM items
 		WHERE id = $1
 `
 
+	ExistingItemIDsQuery = `
+		SELECT id
+		FROM items
+		WHERE id = ANY($1)
+`
+
+	// FindSimilarItemsQuery matches candidates, passed as parallel arrays,
+	// to stored items of the same type, amount and category dated within $8
+	// seconds. Items of the candidate's own source are not matched, since
+	// the source tells them apart by external id.
+	FindSimilarItemsQuery = `
+		SELECT c.idx, items.id
+		FROM UNNEST($1::int[], $2::uuid[], $3::text[], $4::int[], $5::text[], $6::timestamptz[], $7::text[])
+		         AS c(idx, id, type, amount, category, date, source)
+		JOIN items
+		  ON items.type = c.type
+		 AND items.amount = c.amount
+		 AND items.category = c.category
+		 AND items.date BETWEEN c.date - MAKE_INTERVAL(secs => $8) AND c.date + MAKE_INTERVAL(secs => $8)
+		 AND items.id <> c.id
+		 AND (c.source = '' OR items.source IS DISTINCT FROM c.source)
+		ORDER BY c.idx, items.date, items.id
+`
+
 	UpdateItemQuery = `
 		UPDATE items
 		SET 
@@ -33,7 +123,7 @@ const (
 			category = COALESCE($5, category),
 			updated_at = NOW()
 		WHERE id = $1
-		RETURNING id, type, amount, date, category, created_at, updated_at
+		RETURNING id, type, amount, date, category, COALESCE(source, ''), COALESCE(external_id, ''), created_at, updated_at
 `
 
 	DeleteItemQuery = `
@@ -53,6 +143,8 @@ const (
            amount,
            date,
            category,
+           COALESCE(source, ''),
+           COALESCE(external_id, ''),
            created_at,
            updated_at
     FROM items
@@ -64,50 +156,38 @@ const (
 			AVG(amount) as avg,
 			COUNT(*) as count,
 			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY amount) as median,
-			PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY amount) as percentile_90
+			PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY amount) as percentile_90,
+			COALESCE(SUM(amount) FILTER (WHERE type = 'income'), 0) as income,
+			COALESCE(SUM(amount) FILTER (WHERE type = 'expense'), 0) as expense,
+			COUNT(*) FILTER (WHERE type = 'income') as income_count,
+			COUNT(*) FILTER (WHERE type = 'expense') as expense_count
 		FROM items
 		%s
 	`
 
-	AnalyticsGroupedByDayQuery = `
+	AnalyticsGroupedQuery = `
 		SELECT 
-			DATE(date) as group_key,
+			%s as group_key,
 			COALESCE(SUM(amount), 0) as sum,
 			AVG(amount) as avg,
 			COUNT(*) as count,
 			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY amount) as median,
-			PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY amount) as percentile_90
+			PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY amount) as percentile_90,
+			COALESCE(SUM(amount) FILTER (WHERE type = 'income'), 0) as income,
+			COALESCE(SUM(amount) FILTER (WHERE type = 'expense'), 0) as expense,
+			COUNT(*) FILTER (WHERE type = 'income') as income_count,
+			COUNT(*) FILTER (WHERE type = 'expense') as expense_count
 		FROM items
 		%s
-		GROUP BY DATE(date)
-		ORDER BY DATE(date)
+		GROUP BY 1
+		ORDER BY 1
 	`
 
-	AnalyticsGroupedByWeekQuery = `
-		SELECT 
-			DATE_TRUNC('week', date) as group_key,
-			COALESCE(SUM(amount), 0) as sum,
-			AVG(amount) as avg,
-			COUNT(*) as count,
-			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY amount) as median,
-			PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY amount) as percentile_90
-		FROM items
-		%s
-		GROUP BY DATE_TRUNC('week', date)
-		ORDER BY DATE_TRUNC('week', date)
-	`
-
-	AnalyticsGroupedByCategoryQuery = `
-		SELECT 
-			category as group_key,
-			COALESCE(SUM(amount), 0) as sum,
-			AVG(amount) as avg,
-			COUNT(*) as count,
-			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY amount) as median,
-			PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY amount) as percentile_90
+	AnalyticsPivotQuery = `
+		SELECT %s
 		FROM items
 		%s
-		GROUP BY category
-		ORDER BY category
+		GROUP BY GROUPING SETS (%s)
+		ORDER BY %s
 	`
 )
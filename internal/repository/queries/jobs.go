@@ -0,0 +1,152 @@
+package queries
+
+const (
+	CreateJobQuery = `
+		INSERT INTO jobs (id, type, params, file, total_bytes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING status,
+		          created_at,
+		          updated_at
+`
+
+	GetJobQuery = `
+		SELECT id,
+		       type,
+		       status,
+		       params,
+		       total_bytes,
+		       read_bytes,
+		       phase,
+		       processed_rows,
+		       total_rows,
+		       attempts,
+		       result,
+		       error,
+		       created_at,
+		       started_at,
+		       finished_at,
+		       updated_at
+		FROM jobs
+		WHERE id = $1
+`
+
+	// ClaimJobQuery marks the oldest queued job as running. SKIP LOCKED lets
+	// several workers claim jobs at once without picking the same one.
+	ClaimJobQuery = `
+		UPDATE jobs
+		SET status = 'running',
+		    read_bytes = 0,
+		    phase = '',
+		    processed_rows = 0,
+		    total_rows = 0,
+		    attempts = attempts + 1,
+		    started_at = NOW(),
+		    updated_at = NOW()
+		WHERE id = (SELECT id
+		            FROM jobs
+		            WHERE status = 'queued'
+		            ORDER BY created_at
+		            LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING id,
+		          type,
+		          status,
+		          params,
+		          file,
+		          total_bytes,
+		          read_bytes,
+		          phase,
+		          processed_rows,
+		          total_rows,
+		          attempts,
+		          result,
+		          error,
+		          created_at,
+		          started_at,
+		          finished_at,
+		          updated_at
+`
+
+	UpdateJobProgressQuery = `
+		UPDATE jobs
+		SET read_bytes = $2,
+		    phase = $3,
+		    processed_rows = $4,
+		    total_rows = $5,
+		    updated_at = NOW()
+		WHERE id = $1
+		  AND status = 'running'
+`
+
+	FinishJobQuery = `
+		UPDATE jobs
+		SET status = $2,
+		    read_bytes = CASE WHEN $2 = 'succeeded' THEN total_bytes ELSE read_bytes END,
+		    result = $3,
+		    error = $4,
+		    file = NULL,
+		    finished_at = NOW(),
+		    updated_at = NOW()
+		WHERE id = $1
+		  AND status = 'running'
+`
+
+	// RequeueJobQuery returns a job interrupted by shutdown to the queue
+	// without counting the attempt.
+	RequeueJobQuery = `
+		UPDATE jobs
+		SET status = 'queued',
+		    read_bytes = 0,
+		    phase = '',
+		    processed_rows = 0,
+		    total_rows = 0,
+		    attempts = GREATEST(attempts - 1, 0),
+		    started_at = NULL,
+		    updated_at = NOW()
+		WHERE id = $1
+		  AND status = 'running'
+`
+
+	// RequeueStaleJobsQuery recovers running jobs whose worker stopped
+	// reporting progress, such as after a crash. Jobs out of attempts fail.
+	RequeueStaleJobsQuery = `
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= $2 THEN 'failed' ELSE 'queued' END,
+		    read_bytes = 0,
+		    phase = '',
+		    processed_rows = 0,
+		    total_rows = 0,
+		    error = CASE WHEN attempts >= $2 THEN 'job was interrupted too many times' ELSE error END,
+		    file = CASE WHEN attempts >= $2 THEN NULL ELSE file END,
+		    started_at = CASE WHEN attempts >= $2 THEN started_at END,
+		    finished_at = CASE WHEN attempts >= $2 THEN NOW() END,
+		    updated_at = NOW()
+		WHERE status = 'running'
+		  AND updated_at < NOW() - MAKE_INTERVAL(secs => $1)
+`
+
+	CancelJobQuery = `
+		UPDATE jobs
+		SET status = 'cancelled',
+		    file = NULL,
+		    finished_at = NOW(),
+		    updated_at = NOW()
+		WHERE id = $1
+		  AND status IN ('queued', 'running')
+		RETURNING id,
+		          type,
+		          status,
+		          params,
+		          total_bytes,
+		          read_bytes,
+		          phase,
+		          processed_rows,
+		          total_rows,
+		          attempts,
+		          result,
+		          error,
+		          created_at,
+		          started_at,
+		          finished_at,
+		          updated_at
+`
+)
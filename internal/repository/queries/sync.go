@@ -0,0 +1,53 @@
+package queries
+
+const (
+	GetSyncStatesQuery = `
+		SELECT source,
+		       last_change_at,
+		       last_sync_at,
+		       last_success_at,
+		       last_error,
+		       last_count,
+		       last_skipped,
+		       last_skipped_reason,
+		       updated_at
+		FROM wb_sync_state
+		ORDER BY source
+`
+
+	GetSyncStateQuery = `
+		SELECT source,
+		       last_change_at,
+		       last_sync_at,
+		       last_success_at,
+		       last_error,
+		       last_count,
+		       last_skipped,
+		       last_skipped_reason,
+		       updated_at
+		FROM wb_sync_state
+		WHERE source = $1
+`
+
+	SaveSyncStateQuery = `
+		INSERT INTO wb_sync_state (source,
+		                           last_change_at,
+		                           last_sync_at,
+		                           last_success_at,
+		                           last_error,
+		                           last_count,
+		                           last_skipped,
+		                           last_skipped_reason,
+		                           updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (source) DO UPDATE
+		SET last_change_at = EXCLUDED.last_change_at,
+		    last_sync_at = EXCLUDED.last_sync_at,
+		    last_success_at = EXCLUDED.last_success_at,
+		    last_error = EXCLUDED.last_error,
+		    last_count = EXCLUDED.last_count,
+		    last_skipped = EXCLUDED.last_skipped,
+		    last_skipped_reason = EXCLUDED.last_skipped_reason,
+		    updated_at = NOW()
+`
+)
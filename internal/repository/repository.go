@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gookit/slog"
@@ -12,12 +13,29 @@ import (
 
 type ItemManager interface {
 	CreateItem(ctx context.Context, item models.Item) error
+	UpsertItems(ctx context.Context, items []models.Item) (int, int, error)
+	UpsertItemByExternalID(ctx context.Context, item models.Item) (*models.Item, bool, error)
 	GetItemByID(ctx context.Context, id uuid.UUID) (*models.Item, error)
+	ExistingItemIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error)
+	FindSimilarItems(ctx context.Context, items []models.Item, window time.Duration) (map[int][]uuid.UUID, error)
 	GetItems(ctx context.Context, req dto.GetItemsRequest) ([]*models.Item, int, error)
 	UpdateItem(ctx context.Context, id uuid.UUID, req dto.UpdateItemRequest) (*models.Item, error)
 	DeleteItem(ctx context.Context, id uuid.UUID) error
-	GetItemsForExport(ctx context.Context, req dto.GetItemsRequest) ([]*models.Item, error)
+	ExportItems(ctx context.Context, req dto.GetItemsRequest, fn func(*models.Item) error) error
 	GetAnalytics(ctx context.Context, req dto.AnalyticsRequest) (*dto.AnalyticsResponse, error)
+	GetItemsAnalytics(ctx context.Context, req dto.GetItemsRequest) (*dto.AnalyticsResponse, error)
+	GetPivot(ctx context.Context, req dto.PivotRequest) (*dto.PivotResponse, error)
+	GetSyncStates(ctx context.Context) ([]models.SyncState, error)
+	GetSyncState(ctx context.Context, source string) (*models.SyncState, error)
+	SaveSyncState(ctx context.Context, state models.SyncState) error
+	CreateJob(ctx context.Context, job *models.Job) error
+	GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	ClaimJob(ctx context.Context) (*models.Job, error)
+	UpdateJobProgress(ctx context.Context, id uuid.UUID, progress models.JobProgress) (bool, error)
+	FinishJob(ctx context.Context, id uuid.UUID, status string, result []byte, errMsg string) error
+	RequeueJob(ctx context.Context, id uuid.UUID) error
+	RequeueStaleJobs(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int, error)
+	CancelJob(ctx context.Context, id uuid.UUID) (*models.Job, error)
 }
 
 type Repository struct {
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+	"github.com/kstsm/wb-sales-tracker/internal/repository/queries"
+)
+
+func (r *Repository) GetSyncStates(ctx context.Context) ([]models.SyncState, error) {
+	rows, err := r.conn.Query(ctx, queries.GetSyncStatesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("Query-GetSyncStates: %w", err)
+	}
+	defer rows.Close()
+
+	var states []models.SyncState
+	for rows.Next() {
+		var state models.SyncState
+		if err = rows.Scan(
+			&state.Source,
+			&state.LastChangeAt,
+			&state.LastSyncAt,
+			&state.LastSuccessAt,
+			&state.LastError,
+			&state.LastCount,
+			&state.LastSkipped,
+			&state.LastSkippedReason,
+			&state.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("Scan-GetSyncStates: %w", err)
+		}
+		states = append(states, state)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Err-GetSyncStates: %w", err)
+	}
+
+	return states, nil
+}
+
+// GetSyncState returns the state of source, or nil if it has never synced.
+func (r *Repository) GetSyncState(ctx context.Context, source string) (*models.SyncState, error) {
+	var state models.SyncState
+
+	err := r.conn.QueryRow(ctx, queries.GetSyncStateQuery, source).Scan(
+		&state.Source,
+		&state.LastChangeAt,
+		&state.LastSyncAt,
+		&state.LastSuccessAt,
+		&state.LastError,
+		&state.LastCount,
+		&state.LastSkipped,
+		&state.LastSkippedReason,
+		&state.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("QueryRow-GetSyncState: %w", err)
+	}
+
+	return &state, nil
+}
+
+func (r *Repository) SaveSyncState(ctx context.Context, state models.SyncState) error {
+	_, err := r.conn.Exec(ctx, queries.SaveSyncStateQuery,
+		state.Source,
+		state.LastChangeAt,
+		state.LastSyncAt,
+		state.LastSuccessAt,
+		state.LastError,
+		state.LastCount,
+		state.LastSkipped,
+		state.LastSkippedReason,
+	)
+	if err != nil {
+		return fmt.Errorf("Exec-SaveSyncState: %w", err)
+	}
+
+	return nil
+}
@@ -2,10 +2,191 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
 
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
 	"github.com/kstsm/wb-sales-tracker/internal/dto"
 )
 
+const (
+	maxFilledGroups  = 10000
+	hoursPerDay      = 24
+	daysPerWeek      = 7
+	monthsPerQuarter = 3
+	monthsPerYear    = 12
+)
+
 func (s *Service) GetAnalytics(ctx context.Context, req dto.AnalyticsRequest) (*dto.AnalyticsResponse, error) {
-	return s.repo.GetAnalytics(ctx, req)
+	result, err := s.repo.GetAnalytics(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GroupBy != nil && req.Fill != nil && *req.Fill != "none" {
+		var filled bool
+		result.Grouped, filled, err = fillGroupedAnalytics(result.Grouped, req)
+		if errors.Is(err, errGroupKeyMismatch) {
+			s.log.Warnf("GetAnalytics: series left sparse: %v", err)
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result.Fill = *req.Fill
+		if !filled {
+			result.Fill = "none"
+		}
+	}
+
+	if req.Compare != nil {
+		if err = s.compareAnalytics(ctx, req, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (s *Service) GetPivot(ctx context.Context, req dto.PivotRequest) (*dto.PivotResponse, error) {
+	return s.repo.GetPivot(ctx, req)
+}
+
+// errGroupKeyMismatch is returned along with the sparse series when a group
+// does not match any bucket of the period.
+var errGroupKeyMismatch = errors.New("group does not match the generated buckets")
+
+// fillGroupedAnalytics returns a dense series with one bucket for every group
+// between req.From and req.To. Missing buckets get zero totals or nulls
+// depending on req.Fill. Non-time groupings are returned unchanged; the second
+// result reports whether the series was filled.
+func fillGroupedAnalytics(
+	grouped []dto.GroupedAnalytics,
+	req dto.AnalyticsRequest,
+) ([]dto.GroupedAnalytics, bool, error) {
+	keys, err := analyticsGroupKeys(req)
+	if err != nil || keys == nil {
+		return grouped, false, err
+	}
+
+	byKey := make(map[string]dto.GroupedAnalytics, len(grouped))
+	for _, g := range grouped {
+		byKey[g.Group] = g
+	}
+
+	filled := make([]dto.GroupedAnalytics, 0, len(keys))
+	for _, key := range keys {
+		if g, ok := byKey[key]; ok {
+			filled = append(filled, g)
+			delete(byKey, key)
+			continue
+		}
+		filled = append(filled, emptyGroupedAnalytics(key, *req.Fill == "zero"))
+	}
+
+	// A bucket that does not match any generated key means the series cannot
+	// be filled reliably, so the sparse result is returned rather than losing data.
+	if len(byKey) > 0 {
+		return grouped, false, fmt.Errorf("%w: '%s'", errGroupKeyMismatch, slices.Sorted(maps.Keys(byKey))[0])
+	}
+
+	return filled, true, nil
+}
+
+func emptyGroupedAnalytics(group string, zero bool) dto.GroupedAnalytics {
+	g := dto.GroupedAnalytics{Group: group}
+	if zero {
+		var sum, income, expense, net float64
+		g.Sum, g.Income, g.Expense, g.Net = &sum, &income, &expense, &net
+	}
+
+	return g
+}
+
+// analyticsGroupKeys lists the labels of every time bucket in the requested
+// range, computed on local wall-clock time the same way the SQL grouping is.
+func analyticsGroupKeys(req dto.AnalyticsRequest) ([]string, error) {
+	groupBy := *req.GroupBy
+
+	switch groupBy {
+	case "hour":
+		keys := make([]string, 0, hoursPerDay)
+		for h := range hoursPerDay {
+			keys = append(keys, converter.FormatGroupKey(int32(h), groupBy))
+		}
+		return keys, nil
+	case "weekday":
+		keys := make([]string, 0, daysPerWeek)
+		for d := 1; d <= daysPerWeek; d++ {
+			keys = append(keys, converter.FormatGroupKey(int32(d), groupBy))
+		}
+		return keys, nil
+	}
+
+	if req.From == nil || req.To == nil {
+		return nil, nil
+	}
+
+	location := req.Location
+	if location == nil {
+		location = time.UTC
+	}
+	from := localDate(req.From.In(location))
+	to := localDate(req.To.In(location)).Add(hoursPerDay*time.Hour - time.Nanosecond)
+
+	start, next := groupStep(groupBy, from)
+	if next == nil {
+		return nil, nil
+	}
+
+	var keys []string
+	for t := start; !t.After(to); t = next(t) {
+		if len(keys) == maxFilledGroups {
+			return nil, apperrors.ErrTooManyGroups
+		}
+		keys = append(keys, converter.FormatGroupKey(t, groupBy))
+	}
+
+	return keys, nil
+}
+
+// groupStep returns the first bucket start for groupBy at or before from and a
+// function advancing a bucket start to the next one. next is nil for groupings
+// that are not time based.
+func groupStep(groupBy string, from time.Time) (time.Time, func(time.Time) time.Time) {
+	switch groupBy {
+	case "day":
+		return from, func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case "week":
+		offset := (int(from.Weekday()) + daysPerWeek - 1) % daysPerWeek
+		return from.AddDate(0, 0, -offset), func(t time.Time) time.Time { return t.AddDate(0, 0, daysPerWeek) }
+	case "month":
+		start := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	case "quarter":
+		month := (int(from.Month())-1)/monthsPerQuarter*monthsPerQuarter + 1
+		start := time.Date(from.Year(), time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		return start, func(t time.Time) time.Time { return t.AddDate(0, monthsPerQuarter, 0) }
+	case "year":
+		start := time.Date(from.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return start, func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }
+	}
+
+	step, ok := converter.GroupIntervalDuration(groupBy)
+	if !ok {
+		return from, nil
+	}
+
+	return from, func(t time.Time) time.Time { return t.Add(step) }
+}
+
+// localDate returns midnight of t's calendar day as a UTC wall-clock time,
+// matching how Postgres returns timestamps without time zone.
+func localDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
 }
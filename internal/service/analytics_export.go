@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/pkg/export"
+)
+
+const (
+	xlsxAnalyticsSheet  = "Analytics"
+	xlsxParametersSheet = "Parameters"
+)
+
+// analyticsComparisonColumns are the analytics columns that are only
+// exported when a comparison was requested.
+var analyticsComparisonColumns = []string{
+	"previous_group",
+	"sum_previous", "sum_change_pct",
+	"count_previous", "count_change_pct",
+	"income_previous", "income_change_pct",
+	"expense_previous", "expense_change_pct",
+	"net_previous", "net_change_pct",
+}
+
+// ExportAnalyticsCSV writes the grouped buckets and the totals computed by
+// GetAnalytics as CSV.
+func (s *Service) ExportAnalyticsCSV(ctx context.Context, w io.Writer, req dto.AnalyticsRequest) error {
+	result, err := s.GetAnalytics(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.writeAnalyticsCSV(w, result, export.Dialect{})
+	return err
+}
+
+// writeAnalyticsCSV renders result in the given dialect and returns the
+// number of records written, not counting the header.
+func (s *Service) writeAnalyticsCSV(w io.Writer, result *dto.AnalyticsResponse, dialect export.Dialect) (int, error) {
+	writer, err := dialect.NewCSVWriter(w)
+	if err != nil {
+		return 0, err
+	}
+
+	columns, err := analyticsColumns(s.exporter.WithDialect(dialect), result)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := slices.Values(converter.AnalyticsToExportRows(result))
+
+	return export.WriteCSVSeq(writer, localizeColumns(columns, dialect.HeaderLanguage), rows)
+}
+
+// ExportAnalyticsXLSX writes the analytics rows as a workbook with numeric
+// cells and a second sheet listing the request parameters.
+func (s *Service) ExportAnalyticsXLSX(ctx context.Context, w io.Writer, req dto.AnalyticsRequest) error {
+	result, err := s.GetAnalytics(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	columns, err := analyticsColumns(s.exporter, result)
+	if err != nil {
+		return err
+	}
+
+	xw := export.NewXLSXWriter(w)
+	rows := slices.Values(converter.AnalyticsToExportRows(result))
+	if _, err = export.WriteSheetSeq(xw, xlsxAnalyticsSheet, columns, rows); err != nil {
+		return err
+	}
+
+	if err = writeAnalyticsParameters(xw, req, result); err != nil {
+		return err
+	}
+
+	return xw.Close()
+}
+
+// ExportAnalyticsJSON writes the same document as GET /api/analytics.
+func (s *Service) ExportAnalyticsJSON(ctx context.Context, w io.Writer, req dto.AnalyticsRequest) error {
+	result, err := s.GetAnalytics(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		return fmt.Errorf("json.Encode: %w", err)
+	}
+
+	return nil
+}
+
+// analyticsColumns derives the columns from the csv tags of
+// dto.AnalyticsExportRow, without the comparison columns unless result has a
+// comparison. Empty values stay empty cells.
+func analyticsColumns(
+	exporter *export.Exporter,
+	result *dto.AnalyticsResponse,
+) ([]export.Column[dto.AnalyticsExportRow], error) {
+	columns, err := export.StructColumns[dto.AnalyticsExportRow](exporter)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Comparison != nil {
+		return columns, nil
+	}
+
+	return slices.DeleteFunc(columns, func(col export.Column[dto.AnalyticsExportRow]) bool {
+		return slices.Contains(analyticsComparisonColumns, col.Header)
+	}), nil
+}
+
+func writeAnalyticsParameters(xw *export.XLSXWriter, req dto.AnalyticsRequest, result *dto.AnalyticsResponse) error {
+	if err := xw.AddSheet(xlsxParametersSheet, []string{"parameter", "value"}); err != nil {
+		return err
+	}
+
+	optional := func(s *string) export.Cell {
+		if s == nil {
+			return export.EmptyCell()
+		}
+		return export.StringCell(*s)
+	}
+
+	rows := []keyValueRow{
+		{"from", export.StringCell(result.From)},
+		{"to", export.StringCell(result.To)},
+		{"tz", export.StringCell(result.Timezone)},
+		{"type", optional(req.Type)},
+		{"group_by", optional(req.GroupBy)},
+		{"fill", optional(req.Fill)},
+		{"compare", optional(req.Compare)},
+	}
+
+	if result.Comparison != nil {
+		rows = append(rows,
+			keyValueRow{"compare_from", export.StringCell(result.Comparison.From)},
+			keyValueRow{"compare_to", export.StringCell(result.Comparison.To)},
+		)
+	}
+
+	return writeKeyValueRows(xw, rows)
+}
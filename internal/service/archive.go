@@ -0,0 +1,177 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+)
+
+const (
+	archiveItemsFile      = "items.csv"
+	archiveByCategoryFile = "analytics_by_category.csv"
+	archiveByDayFile      = "analytics_by_day.csv"
+	archiveManifestFile   = "manifest.json"
+)
+
+// ExportItemsZIP writes the accounting handoff archive: the items, totals by
+// category and by day, and a manifest with the filter, row counts and
+// checksums. The period covers whole days of req.From and req.To in
+// req.Location so that every file describes the same set of items.
+func (s *Service) ExportItemsZIP(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error {
+	if req.From == nil || req.To == nil {
+		return errors.New("archive export requires both from and to")
+	}
+
+	start, end, location := wholeDays(req)
+
+	itemsReq := req
+	itemsReq.From, itemsReq.To = &start, &end
+
+	analyticsReq := func(groupBy string) dto.AnalyticsRequest {
+		return dto.AnalyticsRequest{
+			From:     &start,
+			To:       &end,
+			Type:     req.Type,
+			Category: req.Category,
+			GroupBy:  &groupBy,
+			Location: location,
+		}
+	}
+
+	byCategory, err := s.GetAnalytics(ctx, analyticsReq("category"))
+	if err != nil {
+		return err
+	}
+
+	fillZero := "zero"
+	byDayReq := analyticsReq("day")
+	byDayReq.Fill = &fillZero
+
+	byDay, err := s.GetAnalytics(ctx, byDayReq)
+	if err != nil {
+		return err
+	}
+
+	generatedAt := time.Now().UTC()
+	dialect := csvDialect(req)
+
+	manifest := dto.ExportManifest{
+		GeneratedAt: generatedAt.Format(time.RFC3339),
+		Filter: dto.ExportManifestFilter{
+			From:     start.Format(time.RFC3339),
+			To:       end.Format(time.RFC3339),
+			Timezone: location.String(),
+			Type:     req.Type,
+			Category: req.Category,
+		},
+	}
+
+	// Rows counts data rows: items, or analytics groups without the totals
+	// row.
+	files := []struct {
+		name  string
+		write func(w io.Writer) (int, error)
+	}{
+		{archiveItemsFile, func(w io.Writer) (int, error) {
+			return s.writeItemsCSV(ctx, w, itemsReq)
+		}},
+		{archiveByCategoryFile, func(w io.Writer) (int, error) {
+			_, writeErr := s.writeAnalyticsCSV(w, byCategory, dialect)
+			return len(byCategory.Grouped), writeErr
+		}},
+		{archiveByDayFile, func(w io.Writer) (int, error) {
+			_, writeErr := s.writeAnalyticsCSV(w, byDay, dialect)
+			return len(byDay.Grouped), writeErr
+		}},
+	}
+
+	zw := zip.NewWriter(w)
+
+	for _, file := range files {
+		entry, createErr := createArchiveEntry(zw, file.name, generatedAt)
+		if createErr != nil {
+			return createErr
+		}
+
+		hash := sha256.New()
+		size := &countingWriter{}
+
+		rows, writeErr := file.write(io.MultiWriter(entry, hash, size))
+		if writeErr != nil {
+			return fmt.Errorf("%s: %w", file.name, writeErr)
+		}
+
+		manifest.Files = append(manifest.Files, dto.ExportManifestFile{
+			Name:   file.name,
+			Rows:   rows,
+			Size:   size.n,
+			SHA256: hex.EncodeToString(hash.Sum(nil)),
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	entry, err := createArchiveEntry(zw, archiveManifestFile, generatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err = entry.Write(data); err != nil {
+		return fmt.Errorf("%s: %w", archiveManifestFile, err)
+	}
+
+	if err = zw.Close(); err != nil {
+		return fmt.Errorf("zip.Close: %w", err)
+	}
+
+	return nil
+}
+
+// wholeDays widens the required req.From and req.To to the start and the end
+// of their days in req.Location.
+func wholeDays(req dto.ExportItemsRequest) (time.Time, time.Time, *time.Location) {
+	location := req.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	from, to := req.From.In(location), req.To.In(location)
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, location)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 23, 59, 59, 999999999, location)
+
+	return start, end, location
+}
+
+func createArchiveEntry(zw *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	entry, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("zip.CreateHeader: %w", err)
+	}
+
+	return entry, nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
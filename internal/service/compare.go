@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+)
+
+// compareAnalytics runs the same aggregation over the window selected by
+// req.Compare and attaches deltas to the totals and to every grouped bucket.
+func (s *Service) compareAnalytics(
+	ctx context.Context,
+	req dto.AnalyticsRequest,
+	current *dto.AnalyticsResponse,
+) error {
+	prevReq := comparisonRequest(req)
+	prevReq.Fill = nil
+
+	previous, err := s.repo.GetAnalytics(ctx, prevReq)
+	if err != nil {
+		return err
+	}
+
+	current.Comparison = &dto.AnalyticsComparison{
+		Mode:    *req.Compare,
+		From:    prevReq.From.Format(time.RFC3339),
+		To:      prevReq.To.Format(time.RFC3339),
+		Metrics: metricDeltas(totalMetrics(current), totalMetrics(previous)),
+	}
+
+	if req.GroupBy == nil {
+		return nil
+	}
+
+	prevByGroup := make(map[string]dto.GroupedAnalytics, len(previous.Grouped))
+	for _, g := range previous.Grouped {
+		prevByGroup[g.Group] = g
+	}
+
+	prevGroupOf, err := previousGroupMapper(req, prevReq)
+	if err != nil {
+		return err
+	}
+
+	for i := range current.Grouped {
+		g := &current.Grouped[i]
+		prevGroup, ok := prevGroupOf(g.Group)
+		if !ok {
+			continue
+		}
+
+		prev, found := prevByGroup[prevGroup]
+		if !found {
+			prev = emptyGroupedAnalytics(prevGroup, true)
+		}
+
+		g.PreviousGroup = prevGroup
+		g.Comparison = metricDeltas(groupMetrics(*g), groupMetrics(prev))
+	}
+
+	return nil
+}
+
+// comparisonRequest shifts the request window back by its own length or by
+// one year. A window of whole calendar months, such as a month, quarter or
+// year, moves by months so that it lines up with the previous one; any other
+// window moves by its length in days.
+func comparisonRequest(req dto.AnalyticsRequest) dto.AnalyticsRequest {
+	location := req.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	from, to := req.From.In(location), req.To.In(location)
+	months, wholeMonths := wholeMonthsBetween(from, to)
+
+	var prevFrom, prevTo time.Time
+	switch {
+	case wholeMonths && *req.Compare == "previous_year":
+		prevFrom, prevTo = shiftMonths(from, to, monthsPerYear)
+	case *req.Compare == "previous_year":
+		prevFrom, prevTo = from.AddDate(-1, 0, 0), to.AddDate(-1, 0, 0)
+	case wholeMonths:
+		prevFrom, prevTo = shiftMonths(from, to, months)
+	default:
+		days := int(localDate(to).Sub(localDate(from)).Hours()/hoursPerDay) + 1
+		prevFrom, prevTo = from.AddDate(0, 0, -days), to.AddDate(0, 0, -days)
+	}
+
+	prevReq := req
+	prevReq.From, prevReq.To, prevReq.Compare = &prevFrom, &prevTo, nil
+
+	return prevReq
+}
+
+// wholeMonthsBetween reports whether from starts a month at midnight and to
+// falls on the last day of a month, and how many months the window spans.
+func wholeMonthsBetween(from, to time.Time) (int, bool) {
+	if !from.Equal(time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())) {
+		return 0, false
+	}
+	if localDate(to).AddDate(0, 0, 1).Day() != 1 {
+		return 0, false
+	}
+
+	months := (to.Year()-from.Year())*monthsPerYear + int(to.Month()-from.Month()) + 1
+	if months <= 0 {
+		return 0, false
+	}
+
+	return months, true
+}
+
+// shiftMonths moves a whole-month window back by n months. The end keeps its
+// time of day and lands on the last day of its month.
+func shiftMonths(from, to time.Time, n int) (time.Time, time.Time) {
+	prevFrom := from.AddDate(0, -n, 0)
+	prevTo := time.Date(to.Year(), to.Month()-time.Month(n)+1, 1,
+		to.Hour(), to.Minute(), to.Second(), to.Nanosecond(), to.Location()).AddDate(0, 0, -1)
+
+	return prevFrom, prevTo
+}
+
+// previousGroupMapper matches buckets of the current window to buckets of the
+// previous one. Calendar buckets are matched by position within the window,
+// categorical ones (category, type, hour, weekday) by label.
+func previousGroupMapper(req, prevReq dto.AnalyticsRequest) (func(string) (string, bool), error) {
+	byLabel := func(group string) (string, bool) { return group, true }
+
+	switch *req.GroupBy {
+	case "category", "type", "hour", "weekday":
+		return byLabel, nil
+	}
+
+	keys, err := analyticsGroupKeys(req)
+	if err != nil || keys == nil {
+		return byLabel, err
+	}
+
+	prevKeys, err := analyticsGroupKeys(prevReq)
+	if err != nil {
+		return nil, err
+	}
+
+	position := make(map[string]int, len(keys))
+	for i, key := range keys {
+		position[key] = i
+	}
+
+	return func(group string) (string, bool) {
+		i, ok := position[group]
+		if !ok || i >= len(prevKeys) {
+			return "", false
+		}
+		return prevKeys[i], true
+	}, nil
+}
+
+func totalMetrics(resp *dto.AnalyticsResponse) map[string]*float64 {
+	return map[string]*float64{
+		"sum":           &resp.Sum,
+		"avg":           resp.Avg,
+		"count":         intMetric(resp.Count),
+		"median":        resp.Median,
+		"percentile_90": resp.Percentile90,
+		"income":        &resp.Income,
+		"expense":       &resp.Expense,
+		"net":           &resp.Net,
+		"income_count":  intMetric(resp.IncomeCount),
+		"expense_count": intMetric(resp.ExpenseCount),
+	}
+}
+
+func groupMetrics(g dto.GroupedAnalytics) map[string]*float64 {
+	return map[string]*float64{
+		"sum":           g.Sum,
+		"avg":           g.Avg,
+		"count":         intMetric(g.Count),
+		"median":        g.Median,
+		"percentile_90": g.Percentile90,
+		"income":        g.Income,
+		"expense":       g.Expense,
+		"net":           g.Net,
+		"income_count":  intMetric(g.IncomeCount),
+		"expense_count": intMetric(g.ExpenseCount),
+	}
+}
+
+func metricDeltas(current, previous map[string]*float64) map[string]dto.MetricDelta {
+	const percent = 100
+
+	deltas := make(map[string]dto.MetricDelta, len(current))
+	for name, cur := range current {
+		prev := previous[name]
+		delta := dto.MetricDelta{Previous: prev}
+
+		if cur != nil && prev != nil {
+			absolute := *cur - *prev
+			delta.Absolute = &absolute
+
+			if *prev != 0 {
+				pct := absolute / math.Abs(*prev) * percent
+				delta.Percent = &pct
+			}
+		}
+
+		deltas[name] = delta
+	}
+
+	return deltas
+}
+
+func intMetric(n int) *float64 {
+	v := float64(n)
+	return &v
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+)
+
+// findDuplicates returns, by index in items, the stored items that items
+// may duplicate: same type, amount and category, dated within the
+// configured window and not from the same source. It finds nothing when the
+// window is zero.
+func (s *Service) findDuplicates(ctx context.Context, items []models.Item) (map[int][]uuid.UUID, error) {
+	if s.duplicate.Window <= 0 || len(items) == 0 {
+		return map[int][]uuid.UUID{}, nil
+	}
+
+	return s.repo.FindSimilarItems(ctx, items, s.duplicate.Window)
+}
+
+// holdBackDuplicates removes the suspected duplicates from items and
+// describes them; lines, when given, are the source lines of items.
+func (s *Service) holdBackDuplicates(
+	ctx context.Context,
+	items []models.Item,
+	lines []int,
+) ([]models.Item, []dto.SuspectedDuplicate, error) {
+	suspects, err := s.findDuplicates(ctx, items)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(suspects) == 0 {
+		return items, nil, nil
+	}
+
+	var duplicates []dto.SuspectedDuplicate
+	kept := make([]models.Item, 0, len(items)-len(suspects))
+	for i := range items {
+		ids, ok := suspects[i]
+		if !ok {
+			kept = append(kept, items[i])
+			continue
+		}
+
+		duplicate := dto.SuspectedDuplicate{
+			Item:        converter.ItemToResponse(&items[i]),
+			DuplicateOf: make([]string, len(ids)),
+		}
+		if lines != nil {
+			duplicate.Line = lines[i]
+		}
+		for j, id := range ids {
+			duplicate.DuplicateOf[j] = id.String()
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+
+	return kept, duplicates, nil
+}
+
+func joinIDs(ids []uuid.UUID) string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+
+	return strings.Join(strs, ", ")
+}
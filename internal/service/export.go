@@ -0,0 +1,389 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"time"
+
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+	"github.com/kstsm/wb-sales-tracker/pkg/export"
+)
+
+const (
+	xlsxItemsSheet   = "Items"
+	xlsxSummarySheet = "Summary"
+)
+
+// errStopIteration ends a repository scan when the consumer of an iterator
+// stops early.
+var errStopIteration = errors.New("iteration stopped")
+
+// exportHeaders translates the column names of itemColumns and
+// analyticsColumns by language.
+var exportHeaders = map[string]map[string]string{
+	"ru": {
+		"id":                 "ID",
+		"type":               "Тип",
+		"amount":             "Сумма",
+		"date":               "Дата",
+		"category":           "Категория",
+		"created_at":         "Создано",
+		"updated_at":         "Изменено",
+		"group":              "Группа",
+		"sum":                "Сумма",
+		"avg":                "Среднее",
+		"count":              "Количество",
+		"median":             "Медиана",
+		"percentile_90":      "90-й перцентиль",
+		"income":             "Доход",
+		"expense":            "Расход",
+		"net":                "Баланс",
+		"income_count":       "Доходов",
+		"expense_count":      "Расходов",
+		"previous_group":     "Прошлая группа",
+		"sum_previous":       "Сумма ранее",
+		"sum_change_pct":     "Сумма, изм. %",
+		"count_previous":     "Количество ранее",
+		"count_change_pct":   "Количество, изм. %",
+		"income_previous":    "Доход ранее",
+		"income_change_pct":  "Доход, изм. %",
+		"expense_previous":   "Расход ранее",
+		"expense_change_pct": "Расход, изм. %",
+		"net_previous":       "Баланс ранее",
+		"net_change_pct":     "Баланс, изм. %",
+	},
+}
+
+// ExportItemsCSV writes the filtered items to w as CSV while they are read
+// from the database, so memory use does not depend on the number of rows.
+// req.Columns selects and orders the columns.
+func (s *Service) ExportItemsCSV(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error {
+	_, err := s.writeItemsCSV(ctx, w, req)
+	return err
+}
+
+// writeItemsCSV implements ExportItemsCSV and returns the number of records
+// written, not counting the header.
+func (s *Service) writeItemsCSV(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) (int, error) {
+	dialect := csvDialect(req)
+
+	columns, err := export.SelectColumns(itemColumns(dialect, req.Location), req.Columns)
+	if err != nil {
+		return 0, err
+	}
+
+	writer, err := dialect.NewCSVWriter(w)
+	if err != nil {
+		return 0, err
+	}
+
+	items, iterErr := s.itemsSeq(ctx, req.GetItemsRequest)
+
+	count, err := export.WriteCSVSeq(writer, localizeColumns(columns, dialect.HeaderLanguage), items)
+	if err = errors.Join(iterErr(), err); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// itemsSeq adapts repository.ExportItems to an iterator. The returned
+// function reports the database error, if any, once iteration has finished.
+func (s *Service) itemsSeq(ctx context.Context, req dto.GetItemsRequest) (iter.Seq[*models.Item], func() error) {
+	var err error
+
+	seq := func(yield func(*models.Item) bool) {
+		err = s.repo.ExportItems(ctx, req, func(item *models.Item) error {
+			if !yield(item) {
+				return errStopIteration
+			}
+			return nil
+		})
+		if errors.Is(err, errStopIteration) {
+			err = nil
+		}
+	}
+
+	return seq, func() error { return err }
+}
+
+// itemColumns lists every exportable item column. CSV values follow the
+// dialect; XLSX cells are typed, with dates in location.
+func itemColumns(dialect export.Dialect, location *time.Location) []export.Column[*models.Item] {
+	if location == nil {
+		location = time.UTC
+	}
+
+	return []export.Column[*models.Item]{
+		{
+			Header: "id",
+			Value:  func(item *models.Item) string { return item.ID.String() },
+		},
+		{
+			Header: "type",
+			Value:  func(item *models.Item) string { return item.Type },
+		},
+		{
+			Header: "amount",
+			Value: func(item *models.Item) string {
+				return dialect.FormatFloat(converter.RublesFromKopeks(item.Amount))
+			},
+			Cell: func(item *models.Item) export.Cell {
+				return export.MoneyCell(converter.RublesFromKopeks(item.Amount))
+			},
+		},
+		{
+			Header: "date",
+			Value:  func(item *models.Item) string { return dialect.FormatTime(item.Date) },
+			Cell:   func(item *models.Item) export.Cell { return export.DateCell(item.Date.In(location)) },
+		},
+		{
+			Header: "category",
+			Value:  func(item *models.Item) string { return item.Category },
+		},
+		{
+			Header: "source",
+			Value:  func(item *models.Item) string { return item.Source },
+		},
+		{
+			Header: "external_id",
+			Value:  func(item *models.Item) string { return item.ExternalID },
+		},
+		{
+			Header: "created_at",
+			Value:  func(item *models.Item) string { return dialect.FormatTime(item.CreatedAt) },
+			Cell:   func(item *models.Item) export.Cell { return export.DateCell(item.CreatedAt.In(location)) },
+		},
+		{
+			Header: "updated_at",
+			Value:  func(item *models.Item) string { return dialect.FormatTime(item.UpdatedAt) },
+			Cell:   func(item *models.Item) export.Cell { return export.DateCell(item.UpdatedAt.In(location)) },
+		},
+	}
+}
+
+// csvDialect starts from the requested preset and applies the individual
+// overrides. Dates with a custom layout are shown in the request timezone.
+func csvDialect(req dto.ExportItemsRequest) export.Dialect {
+	name := "default"
+	if req.Dialect != nil {
+		name = *req.Dialect
+	}
+	dialect, _ := export.LookupDialect(name)
+
+	if req.Delimiter != nil {
+		dialect.Delimiter = *req.Delimiter
+	}
+	if req.BOM != nil {
+		dialect.BOM = *req.BOM
+	}
+	if req.Decimal != nil {
+		dialect.DecimalSeparator = *req.Decimal
+	}
+	if req.DateLayout != nil {
+		dialect.DateLayout = *req.DateLayout
+	}
+	if req.HeaderLang != nil {
+		dialect.HeaderLanguage = *req.HeaderLang
+	}
+
+	if dialect.DateLayout != "" {
+		dialect.Location = req.Location
+	}
+
+	return dialect
+}
+
+func localizeColumns[T any](columns []export.Column[T], lang string) []export.Column[T] {
+	names, ok := exportHeaders[lang]
+	if !ok {
+		return columns
+	}
+
+	localized := slices.Clone(columns)
+	for i := range localized {
+		if name, found := names[localized[i].Header]; found {
+			localized[i].Header = name
+		}
+	}
+
+	return localized
+}
+
+// ExportItemsJSON writes the filtered items to w as a single JSON array of
+// dto.ItemResponse, encoding each element as it is read from the database.
+func (s *Service) ExportItemsJSON(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error {
+	bw := bufio.NewWriter(w)
+
+	if _, err := bw.WriteString("["); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	first := true
+	err := s.repo.ExportItems(ctx, req.GetItemsRequest, func(item *models.Item) error {
+		data, marshalErr := json.Marshal(converter.ItemToResponse(item))
+		if marshalErr != nil {
+			return fmt.Errorf("json.Marshal: %w", marshalErr)
+		}
+
+		if !first {
+			if writeErr := bw.WriteByte(','); writeErr != nil {
+				return fmt.Errorf("write: %w", writeErr)
+			}
+		}
+		first = false
+
+		if _, writeErr := bw.Write(data); writeErr != nil {
+			return fmt.Errorf("write: %w", writeErr)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = bw.WriteString("]\n"); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err = bw.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
+
+// ExportItemsNDJSON writes the filtered items to w as newline-delimited JSON,
+// one dto.ItemResponse per line, so the output can be consumed while it is
+// still being produced.
+func (s *Service) ExportItemsNDJSON(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	err := s.repo.ExportItems(ctx, req.GetItemsRequest, func(item *models.Item) error {
+		if encErr := enc.Encode(converter.ItemToResponse(item)); encErr != nil {
+			return fmt.Errorf("json.Encode: %w", encErr)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = bw.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
+
+// ExportItemsXLSX writes the filtered items as an Excel workbook with typed
+// amount and date cells, followed by a sheet with the analytics totals for
+// the same filter. Dates are shown in req.Location.
+func (s *Service) ExportItemsXLSX(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error {
+	location := req.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	columns, err := export.SelectColumns(itemColumns(export.Dialect{}, location), req.Columns)
+	if err != nil {
+		return err
+	}
+
+	summary, err := s.repo.GetItemsAnalytics(ctx, req.GetItemsRequest)
+	if err != nil {
+		return err
+	}
+
+	xw := export.NewXLSXWriter(w)
+	items, iterErr := s.itemsSeq(ctx, req.GetItemsRequest)
+
+	_, err = export.WriteSheetSeq(xw, xlsxItemsSheet, columns, items)
+	if err = errors.Join(iterErr(), err); err != nil {
+		return err
+	}
+
+	if err = writeSummarySheet(xw, summary, req, location); err != nil {
+		return err
+	}
+
+	return xw.Close()
+}
+
+func writeSummarySheet(
+	xw *export.XLSXWriter,
+	summary *dto.AnalyticsResponse,
+	req dto.ExportItemsRequest,
+	location *time.Location,
+) error {
+	if err := xw.AddSheet(xlsxSummarySheet, []string{"metric", "value"}); err != nil {
+		return err
+	}
+
+	optionalDate := func(t *time.Time) export.Cell {
+		if t == nil {
+			return export.EmptyCell()
+		}
+		return export.DateCell(t.In(location))
+	}
+	optionalString := func(s *string) export.Cell {
+		if s == nil {
+			return export.EmptyCell()
+		}
+		return export.StringCell(*s)
+	}
+	optionalMoney := func(v *float64) export.Cell {
+		if v == nil {
+			return export.EmptyCell()
+		}
+		return export.MoneyCell(*v)
+	}
+
+	rows := []keyValueRow{
+		{"from", optionalDate(req.From)},
+		{"to", optionalDate(req.To)},
+		{"type", optionalString(req.Type)},
+		{"category", optionalString(req.Category)},
+		{"timezone", export.StringCell(location.String())},
+		{"sum", export.MoneyCell(summary.Sum)},
+		{"avg", optionalMoney(summary.Avg)},
+		{"count", export.IntCell(summary.Count)},
+		{"median", optionalMoney(summary.Median)},
+		{"percentile_90", optionalMoney(summary.Percentile90)},
+		{"income", export.MoneyCell(summary.Income)},
+		{"expense", export.MoneyCell(summary.Expense)},
+		{"net", export.MoneyCell(summary.Net)},
+		{"income_count", export.IntCell(summary.IncomeCount)},
+		{"expense_count", export.IntCell(summary.ExpenseCount)},
+	}
+
+	return writeKeyValueRows(xw, rows)
+}
+
+// keyValueRow is a row of a two-column XLSX sheet such as a summary or a
+// parameter list.
+type keyValueRow struct {
+	key   string
+	value export.Cell
+}
+
+func writeKeyValueRows(xw *export.XLSXWriter, rows []keyValueRow) error {
+	for _, row := range rows {
+		if err := xw.WriteRow(export.StringCell(row.key), row.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+	"github.com/kstsm/wb-sales-tracker/pkg/export"
+)
+
+const utf8BOM = "\ufeff"
+
+// importColumns are the item columns the importer reads.
+var importColumns = []string{"id", "type", "amount", "date", "category", "source", "external_id", "created_at", "updated_at"}
+
+// ImportItemsCSV loads a file in the /api/export layout, in the default or
+// the excel-ru dialect; dates in the excel-ru layout are read in
+// req.Location. Rows are upserted by
+// source and external_id when they have them, otherwise by id; rows without
+// either get a new id. Invalid lines are reported by line number and, unless
+// req.Mode is best_effort, abort the whole import. Unless
+// req.AllowDuplicates is set, rows that look like stored items are held back
+// and reported.
+func (s *Service) ImportItemsCSV(
+	ctx context.Context,
+	r io.Reader,
+	req dto.ImportItemsRequest,
+) (*dto.ImportItemsResponse, error) {
+	items, lines, lineErrors, err := s.readItemsCSV(r, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.ImportItemsResponse{
+		DryRun: req.DryRun,
+		Total:  len(items) + len(lineErrors),
+		Valid:  len(items),
+		Failed: len(lineErrors),
+		Errors: lineErrors,
+	}
+
+	if len(lineErrors) > 0 && req.Mode != "best_effort" {
+		return resp, nil
+	}
+
+	if !req.AllowDuplicates {
+		if items, resp.SuspectedDuplicates, err = s.holdBackDuplicates(ctx, items, lines); err != nil {
+			return nil, err
+		}
+		resp.Suspected = len(resp.SuspectedDuplicates)
+	}
+
+	if req.DryRun || len(items) == 0 {
+		return resp, nil
+	}
+
+	progress := startImportPhase(ctx, models.JobPhaseWriting, len(items))
+	resp.Inserted, resp.Updated, err = s.repo.UpsertItems(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+	progress.add(len(items))
+
+	return resp, nil
+}
+
+// readItemsCSV returns the valid items along with their lines, and the
+// errors of the invalid lines. The dialect is recognized by the delimiter
+// of the header line, and columns by their English or localized names.
+func (s *Service) readItemsCSV(
+	r io.Reader,
+	req dto.ImportItemsRequest,
+) ([]models.Item, []int, []dto.ImportLineError, error) {
+	br := bufio.NewReader(r)
+	headerLine, err := br.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, nil, fmt.Errorf("%w: cannot read header: %w", apperrors.ErrInvalidImport, err)
+	}
+
+	dialect := export.SniffDialect(headerLine)
+	dialect.Location = req.Location
+
+	reader := csv.NewReader(io.MultiReader(strings.NewReader(headerLine), br))
+	reader.FieldsPerRecord = -1
+	if dialect.Delimiter != 0 {
+		reader.Comma = dialect.Delimiter
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: cannot read header: %w", apperrors.ErrInvalidImport, err)
+	}
+
+	names := importColumnNames()
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, utf8BOM)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if column, ok := names[name]; ok {
+			name = column
+		}
+		columns[name] = i
+	}
+
+	for _, required := range []string{"type", "amount", "date", "category"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, nil, fmt.Errorf("%w: missing column '%s'", apperrors.ErrInvalidImport, required)
+		}
+	}
+
+	var items []models.Item
+	var lines []int
+	var lineErrors []dto.ImportLineError
+	seenIDs := make(map[uuid.UUID]int)
+	seenExternalIDs := make(map[string]int)
+
+	for {
+		record, readErr := reader.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+
+		if readErr != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(readErr, &parseErr) {
+				return nil, nil, nil, fmt.Errorf("%w: %w", apperrors.ErrInvalidImport, readErr)
+			}
+			lineErrors = append(lineErrors, dto.ImportLineError{Line: parseErr.Line, Error: parseErr.Err.Error()})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		rowID := uuid.New()
+		if req.RowIDNamespace != uuid.Nil {
+			rowID = uuid.NewSHA1(req.RowIDNamespace, []byte(strconv.Itoa(line)))
+		}
+
+		item, itemErr := s.parseImportRecord(record, columns, dialect, rowID)
+		if itemErr == nil {
+			if prevLine, ok := seenIDs[item.ID]; ok {
+				itemErr = fmt.Errorf("duplicate id %s, first seen on line %d", item.ID, prevLine)
+			}
+			if prevLine, ok := seenExternalIDs[item.Source+"|"+item.ExternalID]; ok && item.ExternalID != "" {
+				itemErr = fmt.Errorf("duplicate external_id %s, first seen on line %d", item.ExternalID, prevLine)
+			}
+		}
+		if itemErr != nil {
+			lineErrors = append(lineErrors, dto.ImportLineError{Line: line, Error: itemErr.Error()})
+			continue
+		}
+
+		seenIDs[item.ID] = line
+		if item.ExternalID != "" {
+			seenExternalIDs[item.Source+"|"+item.ExternalID] = line
+		}
+		items = append(items, item)
+		lines = append(lines, line)
+	}
+
+	return items, lines, lineErrors, nil
+}
+
+// importColumnNames maps the lowercased localized headers of export to the
+// column names.
+func importColumnNames() map[string]string {
+	names := make(map[string]string)
+	for _, headers := range exportHeaders {
+		for _, column := range importColumns {
+			if header, ok := headers[column]; ok {
+				names[strings.ToLower(header)] = column
+			}
+		}
+	}
+
+	return names
+}
+
+// parseImportTime reads an RFC3339 time or one in the dialect layout.
+func parseImportTime(s string, dialect export.Dialect) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return dialect.ParseTime(s)
+	}
+
+	return t, nil
+}
+
+// parseImportRecord converts a record to an item with the ID of its id
+// column, or rowID when it has none.
+func (s *Service) parseImportRecord(
+	record []string,
+	columns map[string]int,
+	dialect export.Dialect,
+	rowID uuid.UUID,
+) (models.Item, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	amount, err := converter.ParseRublesAmount(field("amount"))
+	if err != nil {
+		return models.Item{}, err
+	}
+
+	date := field("date")
+	if t, timeErr := parseImportTime(date, dialect); timeErr == nil {
+		date = t.Format(time.RFC3339)
+	}
+
+	req := dto.ImportItemRequest{
+		ID:         field("id"),
+		Type:       field("type"),
+		Amount:     amount,
+		Date:       date,
+		Category:   field("category"),
+		Source:     field("source"),
+		ExternalID: field("external_id"),
+	}
+	if err = s.valid.Struct(req); err != nil {
+		return models.Item{}, errors.New(s.valid.FormatValidationError(err))
+	}
+
+	item, err := newItem(dto.CreateItemRequest{
+		Type:       req.Type,
+		Amount:     req.Amount,
+		Date:       req.Date,
+		Category:   req.Category,
+		Source:     req.Source,
+		ExternalID: req.ExternalID,
+	})
+	if err != nil {
+		return models.Item{}, err
+	}
+
+	item.ID = rowID
+	if req.ID != "" {
+		if item.ID, err = uuid.Parse(req.ID); err != nil {
+			return models.Item{}, fmt.Errorf("invalid id '%s'", req.ID)
+		}
+	}
+
+	if createdAt := field("created_at"); createdAt != "" {
+		if item.CreatedAt, err = parseImportTime(createdAt, dialect); err != nil {
+			return models.Item{}, fmt.Errorf("invalid created_at '%s'", createdAt)
+		}
+	}
+
+	return item, nil
+}
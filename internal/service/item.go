@@ -1,55 +1,129 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/kstsm/wb-sales-tracker/internal/converter"
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
 	"github.com/kstsm/wb-sales-tracker/internal/dto"
 	"github.com/kstsm/wb-sales-tracker/internal/models"
-	"github.com/kstsm/wb-sales-tracker/pkg/export"
 )
 
-func (s *Service) CreateItem(ctx context.Context, req dto.CreateItemRequest) (*models.Item, error) {
-	date, err := time.Parse(time.RFC3339, req.Date)
+// CreateItem stores a new item, or overwrites the item stored under the same
+// source and external id; the flag reports whether an item was created.
+// An ingested item, one with a source, that looks like a stored one is
+// rejected with ErrDuplicate unless req.AllowDuplicate is set. Items entered
+// by hand are not checked: two equal purchases on one day are legitimate.
+func (s *Service) CreateItem(ctx context.Context, req dto.CreateItemRequest) (*models.Item, bool, error) {
+	item, err := newItem(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse date: %w", err)
+		return nil, false, err
+	}
+
+	if item.Source != "" && !req.AllowDuplicate {
+		suspects, findErr := s.findDuplicates(ctx, []models.Item{item})
+		if findErr != nil {
+			return nil, false, findErr
+		}
+		if ids := suspects[0]; len(ids) > 0 {
+			return nil, false, fmt.Errorf("%w of item %s", apperrors.ErrDuplicate, joinIDs(ids))
+		}
 	}
 
-	item := models.Item{
-		ID:        uuid.New(),
-		Type:      req.Type,
-		Amount:    req.Amount,
-		Date:      date,
-		Category:  req.Category,
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
+	if item.ExternalID != "" {
+		return s.repo.UpsertItemByExternalID(ctx, item)
 	}
 
 	if err = s.repo.CreateItem(ctx, item); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	return &item, nil
+	return &item, true, nil
 }
 
-func (s *Service) ExportItemsCSV(ctx context.Context, req dto.GetItemsRequest) ([]byte, error) {
-	items, err := s.repo.GetItemsForExport(ctx, req)
+// CreateItems stores items like CreateItem, in one transaction: an item with
+// a stored source and external id overwrites that item, and one repeating
+// the pair of an earlier one in reqs replaces it. Unless allowDuplicates is
+// set, items that look like stored ones are held back, with or without a
+// source as in the imports, and reported with their index in reqs. It
+// returns the stored items and how many were created.
+func (s *Service) CreateItems(
+	ctx context.Context,
+	reqs []dto.CreateItemRequest,
+	allowDuplicates bool,
+) ([]*models.Item, int, []dto.SuspectedDuplicate, error) {
+	items := make([]models.Item, 0, len(reqs))
+	positions := make(map[uuid.UUID]int, len(reqs))
+	byExternalID := make(map[[2]string]int, len(reqs))
+	for i, req := range reqs {
+		item, err := newItem(req)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		positions[item.ID] = i
+
+		if item.ExternalID != "" {
+			key := [2]string{item.Source, item.ExternalID}
+			if j, ok := byExternalID[key]; ok {
+				items[j] = item
+				continue
+			}
+			byExternalID[key] = len(items)
+		}
+		items = append(items, item)
+	}
+
+	var duplicates []dto.SuspectedDuplicate
+	if !allowDuplicates {
+		var err error
+		if items, duplicates, err = s.holdBackDuplicates(ctx, items, nil); err != nil {
+			return nil, 0, nil, err
+		}
+		for i := range duplicates {
+			id, _ := uuid.Parse(duplicates[i].Item.ID)
+			index := positions[id]
+			duplicates[i].Index = &index
+		}
+	}
+
+	if len(items) == 0 {
+		return []*models.Item{}, 0, duplicates, nil
+	}
+
+	created, _, err := s.repo.UpsertItems(ctx, items)
 	if err != nil {
-		return nil, err
+		return nil, 0, nil, err
 	}
 
-	exportItems := converter.ItemsToResponse(items)
+	result := make([]*models.Item, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
 
-	var buf bytes.Buffer
-	if writeErr := export.WriteItemsCSV(&buf, nil, exportItems); writeErr != nil {
-		return nil, writeErr
+	return result, created, duplicates, nil
+}
+
+func newItem(req dto.CreateItemRequest) (models.Item, error) {
+	date, err := time.Parse(time.RFC3339, req.Date)
+	if err != nil {
+		return models.Item{}, fmt.Errorf("failed to parse date: %w", err)
 	}
 
-	return buf.Bytes(), nil
+	now := time.Now().UTC()
+
+	return models.Item{
+		ID:         uuid.New(),
+		Type:       req.Type,
+		Amount:     req.Amount,
+		Date:       date,
+		Category:   req.Category,
+		Source:     req.Source,
+		ExternalID: req.ExternalID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}, nil
 }
 
 func (s *Service) UpdateItem(ctx context.Context, id uuid.UUID, req dto.UpdateItemRequestInput) (*models.Item, error) {
@@ -81,7 +155,3 @@ func (s *Service) GetItems(ctx context.Context, req dto.GetItemsRequest) ([]*mod
 func (s *Service) DeleteItem(ctx context.Context, id uuid.UUID) error {
 	return s.repo.DeleteItem(ctx, id)
 }
-
-func (s *Service) GetItemsForExport(ctx context.Context, req dto.GetItemsRequest) ([]*models.Item, error) {
-	return s.repo.GetItemsForExport(ctx, req)
-}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gookit/slog"
+	"github.com/kstsm/wb-sales-tracker/config"
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+	"github.com/kstsm/wb-sales-tracker/internal/repository"
+)
+
+const (
+	jobTypeCSV       = "csv"
+	jobTypeStatement = "statement"
+	jobTypeWB        = "wb"
+)
+
+const (
+	// jobHeartbeatInterval is how often a running job stores its progress
+	// and checks whether it was cancelled.
+	jobHeartbeatInterval = 2 * time.Second
+	// jobStaleAfter is how long a running job may go without a heartbeat
+	// before it is considered interrupted and queued again.
+	jobStaleAfter = time.Minute
+	// jobFinishTimeout bounds the final status write, which has to outlive
+	// a shutdown.
+	jobFinishTimeout = 5 * time.Second
+)
+
+var (
+	errJobCancelled   = errors.New("job cancelled")
+	errJobInvalidRows = errors.New("file has invalid rows, nothing was imported")
+	errJobPanicked    = errors.New("import panicked")
+)
+
+type JobManager interface {
+	Run(ctx context.Context)
+	EnqueueImport(ctx context.Context, r io.Reader, req dto.CreateImportJobRequest) (*models.Job, error)
+	GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	CancelJob(ctx context.Context, id uuid.UUID) (*models.Job, error)
+}
+
+// importJobParams is the stored form of the import options. The location
+// of the import is stored by name.
+type importJobParams struct {
+	Items     *dto.ImportItemsRequest     `json:"items,omitempty"`
+	Statement *dto.ImportStatementRequest `json:"statement,omitempty"`
+	WBReport  *dto.ImportWBReportRequest  `json:"wb_report,omitempty"`
+	Timezone  string                      `json:"tz,omitempty"`
+}
+
+// JobRunner runs queued imports in a pool of workers. Jobs live in the
+// database together with their files: a job interrupted by shutdown goes
+// back to the queue at once, and one left running by a crash is queued
+// again when its heartbeat goes stale, until it runs out of attempts.
+// Statement and report imports derive item IDs from the file, and CSV rows
+// without an id get one derived from the job and their line, so a repeated
+// run overwrites what an interrupted one wrote instead of adding copies.
+type JobRunner struct {
+	repo    repository.ItemManager
+	service ItemManager
+	log     *slog.Logger
+	cfg     config.Jobs
+	wake    chan struct{}
+}
+
+func NewJobRunner(
+	repo repository.ItemManager,
+	service ItemManager,
+	log *slog.Logger,
+	cfg config.Jobs,
+) JobManager {
+	return &JobRunner{
+		repo:    repo,
+		service: service,
+		log:     log,
+		cfg:     cfg,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Run starts the workers and recovers stale jobs until ctx is cancelled,
+// then waits for the workers to put their jobs back.
+func (j *JobRunner) Run(ctx context.Context) {
+	j.log.Infof("Starting %d import job workers", j.cfg.Workers)
+
+	var workers sync.WaitGroup
+	for range j.cfg.Workers {
+		workers.Go(func() { j.work(ctx) })
+	}
+
+	ticker := time.NewTicker(jobStaleAfter / 2)
+	defer ticker.Stop()
+
+	for {
+		count, err := j.repo.RequeueStaleJobs(ctx, jobStaleAfter, j.cfg.MaxAttempts)
+		if err != nil && ctx.Err() == nil {
+			j.log.Errorf("Import jobs: recovering stale jobs: %v", err)
+		}
+		if count > 0 {
+			j.log.Warnf("Import jobs: recovered %d interrupted jobs", count)
+			j.notify()
+		}
+
+		select {
+		case <-ctx.Done():
+			workers.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *JobRunner) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := j.repo.ClaimJob(ctx)
+		if err != nil && ctx.Err() == nil {
+			j.log.Errorf("Import jobs: claiming a job: %v", err)
+		}
+		if job != nil {
+			j.runJob(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-j.wake:
+		case <-time.After(j.cfg.PollInterval):
+		}
+	}
+}
+
+// notify wakes an idle worker.
+func (j *JobRunner) notify() {
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (j *JobRunner) runJob(ctx context.Context, job *models.Job) {
+	j.log.Infof("Import job %s (%s) started, attempt %d", job.ID, job.Type, job.Attempts)
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	progress := &importProgress{}
+	progress.start(models.JobPhaseReading, 0)
+	file := &progressReader{ctx: jobCtx, r: bytes.NewReader(job.File)}
+	done := make(chan struct{})
+
+	var heartbeat sync.WaitGroup
+	heartbeat.Go(func() { j.heartbeat(jobCtx, job.ID, file, progress, cancel, done) })
+
+	result, runErr := j.safeRunImport(withImportProgress(jobCtx, progress), job, file)
+	close(done)
+	heartbeat.Wait()
+
+	j.finish(ctx, jobCtx, job, result, runErr)
+}
+
+// heartbeat stores the progress of a job until done is closed and cancels
+// the job once it is no longer running, that is, was cancelled.
+func (j *JobRunner) heartbeat(
+	ctx context.Context,
+	id uuid.UUID,
+	file *progressReader,
+	progress *importProgress,
+	cancel context.CancelCauseFunc,
+	done <-chan struct{},
+) {
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		running, err := j.repo.UpdateJobProgress(ctx, id, progress.snapshot(file.n.Load()))
+		if err != nil {
+			if ctx.Err() == nil {
+				j.log.Errorf("Import job %s: storing progress: %v", id, err)
+			}
+			continue
+		}
+		if !running {
+			cancel(errJobCancelled)
+			return
+		}
+	}
+}
+
+// finish records how a job ended. A job stopped by shutdown goes back to the
+// queue; one cancelled by the user already has its status.
+func (j *JobRunner) finish(ctx, jobCtx context.Context, job *models.Job, result any, runErr error) {
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobFinishTimeout)
+	defer cancel()
+
+	if runErr != nil && errors.Is(context.Cause(jobCtx), errJobCancelled) {
+		j.log.Infof("Import job %s cancelled", job.ID)
+		return
+	}
+
+	if runErr != nil && ctx.Err() != nil {
+		if err := j.repo.RequeueJob(writeCtx, job.ID); err != nil {
+			j.log.Errorf("Import job %s: requeue after shutdown: %v", job.ID, err)
+			return
+		}
+		j.log.Infof("Import job %s interrupted by shutdown, queued again", job.ID)
+		return
+	}
+
+	status, errMsg := models.JobSucceeded, ""
+	switch {
+	case runErr == nil:
+	case errors.Is(runErr, errJobInvalidRows), errors.Is(runErr, apperrors.ErrInvalidImport):
+		status, errMsg = models.JobFailed, runErr.Error()
+	default:
+		j.log.Errorf("Import job %s: %v", job.ID, runErr)
+		status, errMsg = models.JobFailed, "internal error"
+	}
+
+	var resultJSON []byte
+	if result != nil {
+		var err error
+		if resultJSON, err = json.Marshal(result); err != nil {
+			j.log.Errorf("Import job %s: marshal result: %v", job.ID, err)
+		}
+	}
+
+	if err := j.repo.FinishJob(writeCtx, job.ID, status, resultJSON, errMsg); err != nil {
+		j.log.Errorf("Import job %s: storing result: %v", job.ID, err)
+		return
+	}
+
+	j.log.Infof("Import job %s %s", job.ID, status)
+}
+
+// safeRunImport runs the import of job and turns a panic into an error, so
+// that a broken file fails its job instead of the server.
+func (j *JobRunner) safeRunImport(ctx context.Context, job *models.Job, r io.Reader) (result any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, fmt.Errorf("%w: %v\n%s", errJobPanicked, p, debug.Stack())
+		}
+	}()
+
+	return j.runImport(ctx, job, r)
+}
+
+// runImport runs the import of job with its stored options. A CSV import
+// that rejects the file in atomic mode returns its response along with
+// errJobInvalidRows.
+func (j *JobRunner) runImport(ctx context.Context, job *models.Job, r io.Reader) (any, error) {
+	var params importJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return nil, fmt.Errorf("unmarshal params: %w", err)
+	}
+
+	location := time.UTC
+	if params.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(params.Timezone); err != nil {
+			return nil, fmt.Errorf("load location: %w", err)
+		}
+	}
+
+	switch {
+	case job.Type == jobTypeCSV && params.Items != nil:
+		params.Items.Location = location
+		params.Items.RowIDNamespace = job.ID
+		resp, err := j.service.ImportItemsCSV(ctx, r, *params.Items)
+		if err != nil {
+			return nil, err
+		}
+		if !params.Items.DryRun && resp.Failed > 0 && params.Items.Mode != "best_effort" {
+			return resp, errJobInvalidRows
+		}
+		return resp, nil
+	case job.Type == jobTypeStatement && params.Statement != nil:
+		params.Statement.Location = location
+		return j.service.ImportStatement(ctx, r, *params.Statement)
+	case job.Type == jobTypeWB && params.WBReport != nil:
+		params.WBReport.Location = location
+		return j.service.ImportWBReport(ctx, r, *params.WBReport)
+	default:
+		return nil, fmt.Errorf("no options for job type '%s'", job.Type)
+	}
+}
+
+// EnqueueImport stores the file read from r with the import options and
+// wakes a worker to run it.
+func (j *JobRunner) EnqueueImport(
+	ctx context.Context,
+	r io.Reader,
+	req dto.CreateImportJobRequest,
+) (*models.Job, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	params := importJobParams{
+		Items:     req.Items,
+		Statement: req.Statement,
+		WBReport:  req.WBReport,
+	}
+	switch {
+	case req.Items != nil && req.Items.Location != nil:
+		params.Timezone = req.Items.Location.String()
+	case req.Statement != nil && req.Statement.Location != nil:
+		params.Timezone = req.Statement.Location.String()
+	case req.WBReport != nil && req.WBReport.Location != nil:
+		params.Timezone = req.WBReport.Location.String()
+	}
+
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("marshal params: %w", err)
+	}
+
+	job := &models.Job{
+		ID:         uuid.New(),
+		Type:       req.Type,
+		Params:     paramsJSON,
+		File:       data,
+		TotalBytes: int64(len(data)),
+	}
+	if err = j.repo.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	j.notify()
+
+	return job, nil
+}
+
+func (j *JobRunner) GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	return j.repo.GetJob(ctx, id)
+}
+
+// CancelJob cancels a queued or running job. A running job stops at its next
+// heartbeat; items written before that are kept.
+func (j *JobRunner) CancelJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	return j.repo.CancelJob(ctx, id)
+}
+
+// progressReader counts the bytes an import has read and fails the reads
+// once ctx is cancelled, so that parsing stops too.
+type progressReader struct {
+	ctx context.Context
+	r   io.Reader
+	n   atomic.Int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := p.r.Read(b)
+	p.n.Add(int64(n))
+
+	return n, err
+}
+
+// importProgress is the phase of a running import and the rows it has got
+// through, for the job heartbeat. Imports read the whole file before they
+// process it, so bytes alone say little about how far a job is.
+type importProgress struct {
+	phase     atomic.Pointer[string]
+	processed atomic.Int64
+	total     atomic.Int64
+}
+
+type importProgressKey struct{}
+
+func withImportProgress(ctx context.Context, p *importProgress) context.Context {
+	return context.WithValue(ctx, importProgressKey{}, p)
+}
+
+// startImportPhase moves the import run with ctx to phase over total rows.
+// It returns nil, which is safe to use, when the import is not a job.
+func startImportPhase(ctx context.Context, phase string, total int) *importProgress {
+	p, _ := ctx.Value(importProgressKey{}).(*importProgress)
+	p.start(phase, total)
+
+	return p
+}
+
+func (p *importProgress) start(phase string, total int) {
+	if p == nil {
+		return
+	}
+
+	p.processed.Store(0)
+	p.total.Store(int64(total))
+	p.phase.Store(&phase)
+}
+
+// add counts n more processed rows.
+func (p *importProgress) add(n int) {
+	if p != nil {
+		p.processed.Add(int64(n))
+	}
+}
+
+func (p *importProgress) snapshot(readBytes int64) models.JobProgress {
+	progress := models.JobProgress{
+		ReadBytes:     readBytes,
+		ProcessedRows: int(p.processed.Load()),
+		TotalRows:     int(p.total.Load()),
+	}
+	if phase := p.phase.Load(); phase != nil {
+		progress.Phase = *phase
+	}
+
+	return progress
+}
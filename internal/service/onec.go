@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+	"github.com/kstsm/wb-sales-tracker/pkg/export"
+)
+
+const oneCSender = "WB Sales Tracker"
+
+// ExportItemsOneC writes the filtered items as a 1CClientBankExchange
+// statement for our account: income becomes incoming payment orders and
+// expense outgoing ones, numbered in the export sort order. The period
+// covers whole days of req.From and req.To in req.Location.
+func (s *Service) ExportItemsOneC(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error {
+	if s.oneC.Account == "" {
+		return fmt.Errorf("%w: set ONEC_ACCOUNT for 1C export", apperrors.ErrNotConfigured)
+	}
+
+	if req.From == nil || req.To == nil {
+		return errors.New("1C export requires both from and to")
+	}
+
+	start, end, location := wholeDays(req)
+
+	itemsReq := req.GetItemsRequest
+	itemsReq.From, itemsReq.To = &start, &end
+
+	ow, err := export.NewOneCWriter(w, export.OneCStatement{
+		Sender:  oneCSender,
+		Created: time.Now().In(location),
+		From:    start,
+		To:      end,
+		Account: s.oneC.Account,
+	})
+	if err != nil {
+		return err
+	}
+
+	company := export.OneCParty{
+		Name:    s.oneC.CompanyName,
+		INN:     s.oneC.CompanyINN,
+		Account: s.oneC.Account,
+		BIC:     s.oneC.BankBIC,
+	}
+
+	var number int
+	items, iterErr := s.itemsSeq(ctx, itemsReq)
+
+	for item := range items {
+		number++
+		if err = ow.WriteDocument(s.oneCDocument(item, number, company, location)); err != nil {
+			break
+		}
+	}
+
+	if err = errors.Join(iterErr(), err); err != nil {
+		return err
+	}
+
+	return ow.Close()
+}
+
+// oneCDocument maps an item to a payment order between our company and the
+// configured placeholder counterparty.
+func (s *Service) oneCDocument(
+	item *models.Item,
+	number int,
+	company export.OneCParty,
+	location *time.Location,
+) export.OneCDocument {
+	placeholders := strings.NewReplacer("{category}", item.Category)
+
+	counterparty := export.OneCParty{
+		Name:    placeholders.Replace(s.oneC.CounterpartyName),
+		INN:     s.oneC.CounterpartyINN,
+		Account: s.oneC.CounterpartyAccount,
+		BIC:     s.oneC.CounterpartyBIC,
+	}
+
+	doc := export.OneCDocument{
+		Number:   number,
+		Date:     item.Date.In(location),
+		Amount:   converter.RublesFromKopeks(item.Amount),
+		Incoming: item.Type == "income",
+		Purpose:  placeholders.Replace(s.oneC.Purpose),
+	}
+
+	if doc.Incoming {
+		doc.Payer, doc.Payee = counterparty, company
+	} else {
+		doc.Payer, doc.Payee = company, counterparty
+	}
+
+	return doc
+}
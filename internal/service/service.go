@@ -2,33 +2,80 @@ package service
 
 import (
 	"context"
+	"io"
 
 	"github.com/google/uuid"
 	"github.com/gookit/slog"
+	"github.com/kstsm/wb-sales-tracker/config"
 	"github.com/kstsm/wb-sales-tracker/internal/dto"
 	"github.com/kstsm/wb-sales-tracker/internal/models"
 	"github.com/kstsm/wb-sales-tracker/internal/repository"
+	"github.com/kstsm/wb-sales-tracker/pkg/export"
+	"github.com/kstsm/wb-sales-tracker/pkg/validator"
 )
 
 type ItemManager interface {
-	CreateItem(ctx context.Context, req dto.CreateItemRequest) (*models.Item, error)
+	CreateItem(ctx context.Context, req dto.CreateItemRequest) (*models.Item, bool, error)
+	CreateItems(
+		ctx context.Context,
+		reqs []dto.CreateItemRequest,
+		allowDuplicates bool,
+	) ([]*models.Item, int, []dto.SuspectedDuplicate, error)
 	GetItems(ctx context.Context, req dto.GetItemsRequest) ([]*models.Item, int, error)
 	GetItemByID(ctx context.Context, id uuid.UUID) (*models.Item, error)
 	UpdateItem(ctx context.Context, id uuid.UUID, req dto.UpdateItemRequestInput) (*models.Item, error)
 	DeleteItem(ctx context.Context, id uuid.UUID) error
 	GetAnalytics(ctx context.Context, req dto.AnalyticsRequest) (*dto.AnalyticsResponse, error)
-	GetItemsForExport(ctx context.Context, req dto.GetItemsRequest) ([]*models.Item, error)
-	ExportItemsCSV(ctx context.Context, req dto.GetItemsRequest) ([]byte, error)
+	GetPivot(ctx context.Context, req dto.PivotRequest) (*dto.PivotResponse, error)
+	ExportAnalyticsCSV(ctx context.Context, w io.Writer, req dto.AnalyticsRequest) error
+	ExportAnalyticsXLSX(ctx context.Context, w io.Writer, req dto.AnalyticsRequest) error
+	ExportAnalyticsJSON(ctx context.Context, w io.Writer, req dto.AnalyticsRequest) error
+	ExportItemsCSV(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error
+	ExportItemsXLSX(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error
+	ExportItemsJSON(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error
+	ExportItemsNDJSON(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error
+	ExportItemsZIP(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error
+	ExportItemsOneC(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error
+	ExportItemsOFX(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error
+	ExportItemsQIF(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error
+	ImportItemsCSV(ctx context.Context, r io.Reader, req dto.ImportItemsRequest) (*dto.ImportItemsResponse, error)
+	ImportStatement(
+		ctx context.Context,
+		r io.Reader,
+		req dto.ImportStatementRequest,
+	) (*dto.ImportStatementResponse, error)
+	ImportWBReport(
+		ctx context.Context,
+		r io.Reader,
+		req dto.ImportWBReportRequest,
+	) (*dto.ImportStatementResponse, error)
 }
 
 type Service struct {
-	repo repository.ItemManager
-	log  *slog.Logger
+	repo      repository.ItemManager
+	log       *slog.Logger
+	valid     *validator.Validate
+	exporter  *export.Exporter
+	oneC      config.OneC
+	ofx       config.OFX
+	duplicate config.Duplicate
 }
 
-func NewService(repo repository.ItemManager, log *slog.Logger) ItemManager {
+func NewService(
+	repo repository.ItemManager,
+	log *slog.Logger,
+	valid *validator.Validate,
+	oneC config.OneC,
+	ofx config.OFX,
+	duplicate config.Duplicate,
+) ItemManager {
 	return &Service{
-		repo: repo,
-		log:  log,
+		repo:      repo,
+		log:       log,
+		valid:     valid,
+		exporter:  export.NewExporter(),
+		oneC:      oneC,
+		ofx:       ofx,
+		duplicate: duplicate,
 	}
 }
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+	"github.com/kstsm/wb-sales-tracker/pkg/export"
+)
+
+// ExportItemsOFX writes the filtered items as an OFX bank statement for
+// personal-finance tools such as GnuCash and HomeBank. Income is credited,
+// expense debited, and the item ID is the FITID. The period covers whole
+// days of req.From and req.To in req.Location.
+func (s *Service) ExportItemsOFX(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error {
+	if req.From == nil || req.To == nil {
+		return errors.New("OFX export requires both from and to")
+	}
+
+	start, end, location := wholeDays(req)
+
+	itemsReq := req.GetItemsRequest
+	itemsReq.From, itemsReq.To = &start, &end
+
+	ow, err := export.NewOFXWriter(w, export.OFXStatement{
+		BankID:    s.ofx.BankID,
+		AccountID: s.ofx.AccountID,
+		Created:   time.Now(),
+		From:      start,
+		To:        end,
+	})
+	if err != nil {
+		return err
+	}
+
+	items, iterErr := s.itemsSeq(ctx, itemsReq)
+
+	for item := range items {
+		err = ow.WriteTransaction(export.OFXTransaction{
+			ID:     item.ID.String(),
+			Date:   item.Date.In(location),
+			Amount: signedRubles(item),
+			Name:   item.Category,
+		})
+		if err != nil {
+			break
+		}
+	}
+
+	if err = errors.Join(iterErr(), err); err != nil {
+		return err
+	}
+
+	return ow.Close()
+}
+
+// ExportItemsQIF writes the filtered items as QIF !Type:Bank entries with
+// the item category as the QIF category. Dates are in req.Location.
+func (s *Service) ExportItemsQIF(ctx context.Context, w io.Writer, req dto.ExportItemsRequest) error {
+	location := req.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	qw, err := export.NewQIFWriter(w)
+	if err != nil {
+		return err
+	}
+
+	items, iterErr := s.itemsSeq(ctx, req.GetItemsRequest)
+
+	for item := range items {
+		err = qw.WriteTransaction(export.QIFTransaction{
+			Date:     item.Date.In(location),
+			Amount:   signedRubles(item),
+			Category: item.Category,
+		})
+		if err != nil {
+			break
+		}
+	}
+
+	if err = errors.Join(iterErr(), err); err != nil {
+		return err
+	}
+
+	return qw.Close()
+}
+
+// signedRubles returns the item amount in rubles, negative for expenses.
+func signedRubles(item *models.Item) float64 {
+	amount := converter.RublesFromKopeks(item.Amount)
+	if item.Type == "expense" {
+		return -amount
+	}
+
+	return amount
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+	"github.com/kstsm/wb-sales-tracker/pkg/bankfile"
+)
+
+// statementNamespace seeds the item IDs of imported bank transactions, so
+// that importing the same statement twice yields the same IDs.
+var statementNamespace = uuid.MustParse("6f1c1d2e-8a47-4c0e-9d51-3b7a2f4e9c10")
+
+// purposeCategories maps words of the payment purpose to item categories.
+// Keywords match the beginning of a word, and the first matching rule wins.
+var purposeCategories = []struct {
+	keywords []string
+	income   string
+	expense  string
+}{
+	{[]string{"wildberries", "вайлдберриз", "вайлдбериз", "рвб"}, "Выплаты WB", "Услуги WB"},
+	{[]string{"налог", "ндфл", "усн", "фнс", "взнос", "tax"}, "Возврат налогов", "Налоги и взносы"},
+	{[]string{"заработн", "зарплат", "з/п", "salary", "payroll"}, "Зарплата", "Зарплата"},
+	{[]string{"аренд", "rent"}, "Аренда", "Аренда"},
+	{[]string{"логистик", "доставк", "перевозк", "транспорт", "shipping", "delivery"}, "Логистика", "Логистика"},
+	{[]string{"реклам", "продвижени", "advertis"}, "Реклама", "Реклама"},
+	{[]string{"возврат", "refund"}, "Возвраты", "Возвраты"},
+	{[]string{"комисси", "обслуживани", "тариф", "commission", "fee"}, "Банковские операции", "Банковские комиссии"},
+	{[]string{"поставк", "товар", "invoice"}, "Продажи", "Закупка товара"},
+}
+
+const (
+	otherIncomeCategory  = "Прочие поступления"
+	otherExpenseCategory = "Прочие списания"
+)
+
+// maxExternalIDLength is the size of the external_id column.
+const maxExternalIDLength = 128
+
+// rubleCurrencies are the codes of amounts that can be stored as they are.
+// RUR is the pre-1998 code that some banks still write.
+var rubleCurrencies = map[string]bool{"": true, "RUB": true, "RUR": true}
+
+// statementParsers reads statement files by the format parameter.
+var statementParsers = map[string]func(io.Reader, *time.Location) (*bankfile.Statement, error){
+	"1c":      bankfile.ParseOneC,
+	"ofx":     bankfile.ParseOFX,
+	"qif":     bankfile.ParseQIF,
+	"camt053": bankfile.ParseCAMT053,
+}
+
+// ImportStatement loads the transactions of a bank statement file. Every
+// transaction gets an ID derived from its content, so transactions that are
+// already stored, or repeated within the file, are counted as duplicates
+// and left untouched. Records that cannot be imported are skipped and
+// reported; the rest are inserted. A dry run returns the items that would be
+// inserted as a preview.
+func (s *Service) ImportStatement(
+	ctx context.Context,
+	r io.Reader,
+	req dto.ImportStatementRequest,
+) (*dto.ImportStatementResponse, error) {
+	location := req.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	parse, ok := statementParsers[req.Format]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported statement format '%s'", apperrors.ErrInvalidImport, req.Format)
+	}
+
+	stmt, err := parse(r, location)
+	if err != nil {
+		if errors.Is(err, bankfile.ErrInvalidFile) {
+			return nil, fmt.Errorf("%w: %w", apperrors.ErrInvalidImport, err)
+		}
+		return nil, err
+	}
+
+	return s.importTransactions(ctx, stmt, req)
+}
+
+func (s *Service) importTransactions(
+	ctx context.Context,
+	stmt *bankfile.Statement,
+	req dto.ImportStatementRequest,
+) (*dto.ImportStatementResponse, error) {
+	resp := &dto.ImportStatementResponse{
+		DryRun: req.DryRun,
+		Parsed: len(stmt.Transactions) + len(stmt.Skipped),
+	}
+
+	for _, skipped := range stmt.Skipped {
+		resp.Errors = append(resp.Errors, dto.ImportLineError{Line: skipped.Line, Error: skipped.Reason})
+	}
+
+	progress := startImportPhase(ctx, models.JobPhaseProcessing, len(stmt.Transactions))
+
+	items := make([]models.Item, 0, len(stmt.Transactions))
+	for _, tx := range stmt.Transactions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.add(1)
+
+		item, err := s.transactionToItem(req.Format, tx)
+		if err != nil {
+			resp.Errors = append(resp.Errors, dto.ImportLineError{Line: tx.Line, Error: err.Error()})
+			continue
+		}
+		items = append(items, item)
+	}
+
+	resp.Skipped = len(resp.Errors)
+
+	if err := s.saveImportedItems(ctx, resp, items, req.AllowDuplicates); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// saveImportedItems counts the items repeated in items, by ID or by source
+// and external id, or already stored as duplicates and inserts the rest, or
+// lists them in resp on a dry run.
+// Unless allowDuplicates is set, items that look like stored ones are held
+// back and reported instead.
+func (s *Service) saveImportedItems(
+	ctx context.Context,
+	resp *dto.ImportStatementResponse,
+	items []models.Item,
+	allowDuplicates bool,
+) error {
+	ids := make([]uuid.UUID, 0, len(items))
+	seen := make(map[uuid.UUID]bool, len(items))
+	seenExternal := make(map[[2]string]bool, len(items))
+	unique := make([]models.Item, 0, len(items))
+
+	for _, item := range items {
+		external := [2]string{item.Source, item.ExternalID}
+		if seen[item.ID] || item.ExternalID != "" && seenExternal[external] {
+			resp.Duplicates++
+			continue
+		}
+		seen[item.ID] = true
+		if item.ExternalID != "" {
+			seenExternal[external] = true
+		}
+
+		unique = append(unique, item)
+		ids = append(ids, item.ID)
+	}
+
+	existing, err := s.repo.ExistingItemIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	fresh := unique[:0]
+	for _, item := range unique {
+		if existing[item.ID] {
+			resp.Duplicates++
+			continue
+		}
+		fresh = append(fresh, item)
+	}
+
+	if !allowDuplicates {
+		if fresh, resp.SuspectedDuplicates, err = s.holdBackDuplicates(ctx, fresh, nil); err != nil {
+			return err
+		}
+		resp.Suspected = len(resp.SuspectedDuplicates)
+	}
+
+	if resp.DryRun {
+		resp.Items = make([]dto.ItemResponse, len(fresh))
+		for i := range fresh {
+			resp.Items[i] = converter.ItemToResponse(&fresh[i])
+		}
+		return nil
+	}
+
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	progress := startImportPhase(ctx, models.JobPhaseWriting, len(fresh))
+	if resp.Inserted, _, err = s.repo.UpsertItems(ctx, fresh); err != nil {
+		return err
+	}
+	progress.add(len(fresh))
+
+	return nil
+}
+
+// transactionToItem validates tx like any other item and gives it a stable
+// ID. The category comes from the file when it has one, otherwise from the
+// purpose. Amounts are stored in kopeks, so transactions in other
+// currencies are rejected rather than converted.
+func (s *Service) transactionToItem(format string, tx bankfile.Transaction) (models.Item, error) {
+	if !rubleCurrencies[tx.Currency] {
+		return models.Item{}, fmt.Errorf("unsupported currency '%s', only RUB is imported", tx.Currency)
+	}
+
+	amount, err := converter.ParseRublesAmount(tx.Amount)
+	if err != nil {
+		return models.Item{}, err
+	}
+
+	itemType := "expense"
+	if tx.Incoming {
+		itemType = "income"
+	}
+
+	req := dto.ImportItemRequest{
+		Type:     itemType,
+		Amount:   amount,
+		Date:     tx.Date.Format(time.RFC3339),
+		Category: tx.Category,
+	}
+	if req.Category == "" {
+		req.Category = categoryFromPurpose(tx.Purpose, tx.Incoming)
+	}
+	if err = s.valid.Struct(req); err != nil {
+		return models.Item{}, errors.New(s.valid.FormatValidationError(err))
+	}
+
+	item, err := newItem(dto.CreateItemRequest{
+		Type:     req.Type,
+		Amount:   req.Amount,
+		Date:     req.Date,
+		Category: req.Category,
+	})
+	if err != nil {
+		return models.Item{}, err
+	}
+
+	key := strings.Join([]string{
+		format,
+		tx.Account,
+		tx.ID,
+		tx.Date.Format(time.DateOnly),
+		itemType,
+		strconv.Itoa(amount),
+	}, "|")
+	item.ID = uuid.NewSHA1(statementNamespace, []byte(key))
+	if externalID := statementExternalID(format, tx, itemType); externalID != "" {
+		item.Source, item.ExternalID = "bank_"+format, externalID
+	}
+
+	return item, nil
+}
+
+// statementExternalID returns the bank's identifier of tx: the FITID,
+// AcctSvcrRef or document number, prefixed with the account it is unique
+// within. 1C document numbers restart every year and are assigned by the
+// payer, so the date and direction are added for them. QIF has no
+// identifier: the key ParseQIF derives from the fields and the position of a
+// transaction only sets the item ID, since it shifts when an overlapping
+// export gains an equal transaction. Transactions without an identifier or
+// with one too long to store get none.
+func statementExternalID(format string, tx bankfile.Transaction, itemType string) string {
+	if tx.ID == "" || format == "qif" {
+		return ""
+	}
+
+	parts := []string{tx.Account}
+	if format == "1c" {
+		parts = append(parts, tx.Date.Format(time.DateOnly), itemType)
+	}
+	externalID := strings.Join(append(parts, tx.ID), "|")
+
+	if len(externalID) > maxExternalIDLength {
+		return ""
+	}
+
+	return externalID
+}
+
+func categoryFromPurpose(purpose string, incoming bool) string {
+	purpose = strings.ToLower(purpose)
+
+	for _, rule := range purposeCategories {
+		for _, keyword := range rule.keywords {
+			if hasWordPrefix(purpose, keyword) {
+				if incoming {
+					return rule.income
+				}
+				return rule.expense
+			}
+		}
+	}
+
+	if incoming {
+		return otherIncomeCategory
+	}
+
+	return otherExpenseCategory
+}
+
+// hasWordPrefix reports whether a word of s starts with prefix.
+func hasWordPrefix(s, prefix string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], prefix)
+		if j == -1 {
+			return false
+		}
+		i += j
+
+		before, _ := utf8.DecodeLastRuneInString(s[:i])
+		if i == 0 || !unicode.IsLetter(before) && !unicode.IsDigit(before) {
+			return true
+		}
+		i += len(prefix)
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+	"github.com/kstsm/wb-sales-tracker/pkg/wbreport"
+)
+
+// wbNamespace seeds the item IDs of realization report rows, so that
+// importing the same report twice yields the same IDs.
+var wbNamespace = uuid.MustParse("b3e0a6c4-52d1-4f7e-8c9a-1d4e6f20a7b5")
+
+// Categories of the side components of a sale or return row. The main
+// component takes the category from supplier_oper_name.
+const (
+	wbCommissionCategory = "Комиссия WB"
+	wbAcquiringCategory  = "Эквайринг"
+	wbLogisticsCategory  = "Логистика"
+	wbStorageCategory    = "Хранение"
+	wbPenaltyCategory    = "Штрафы"
+)
+
+const (
+	wbReportSource    = "wb_report"
+	maxCategoryLength = 32
+)
+
+// wbComponent is one money flow of a report row, in rubles. Positive
+// amounts are income and negative ones expenses.
+type wbComponent struct {
+	name     string
+	amount   float64
+	category string
+}
+
+// ImportWBReport loads a Wildberries realization report, either the JSON of
+// reportDetailByPeriod or the XLSX from the seller portal, told apart by
+// content. Every row is split into a sale or payout item and separate items
+// for commission, acquiring, logistics, storage and penalties. Item IDs are
+// derived from the report row id, or from the report id and the row number
+// when the file has no row ids, so rows already imported are counted as
+// duplicates. A file that has neither row ids nor report ids is rejected
+// unless req.ReportID is set, since its row numbers repeat every week.
+func (s *Service) ImportWBReport(
+	ctx context.Context,
+	r io.Reader,
+	req dto.ImportWBReportRequest,
+) (*dto.ImportStatementResponse, error) {
+	location := req.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	br := bufio.NewReader(r)
+
+	parse := wbreport.ParseJSON
+	if head, _ := br.Peek(len("PK")); bytes.Equal(head, []byte("PK")) {
+		parse = wbreport.ParseXLSX
+	}
+
+	rows, err := parse(br)
+	if err != nil {
+		if errors.Is(err, wbreport.ErrInvalidReport) {
+			return nil, fmt.Errorf("%w: %w", apperrors.ErrInvalidImport, err)
+		}
+		return nil, err
+	}
+
+	if req.ReportID == 0 {
+		for _, row := range rows {
+			if row.RRDID == 0 && row.ReportID == 0 {
+				return nil, fmt.Errorf(
+					"%w: line %d has no rrd_id or report number, set report_id",
+					apperrors.ErrInvalidImport, row.Line,
+				)
+			}
+		}
+	}
+
+	resp := &dto.ImportStatementResponse{
+		DryRun: req.DryRun,
+		Parsed: len(rows),
+	}
+
+	progress := startImportPhase(ctx, models.JobPhaseProcessing, len(rows))
+
+	items := make([]models.Item, 0, len(rows))
+	for _, row := range rows {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.add(1)
+
+		rowItems, rowErr := s.wbRowToItems(row, req.ReportID, location)
+		if rowErr != nil {
+			resp.Errors = append(resp.Errors, dto.ImportLineError{Line: row.Line, Error: rowErr.Error()})
+			continue
+		}
+		items = append(items, rowItems...)
+	}
+
+	resp.Skipped = len(resp.Errors)
+
+	if err = s.saveImportedItems(ctx, resp, items, req.AllowDuplicates); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// wbRowToItems validates the components of row like any other item. A row
+// without money flows yields no items.
+func (s *Service) wbRowToItems(row wbreport.Row, reportID int64, location *time.Location) ([]models.Item, error) {
+	if row.ReportID != 0 {
+		reportID = row.ReportID
+	}
+
+	var rowKey string
+	switch {
+	case row.RRDID != 0:
+		rowKey = fmt.Sprintf("%d|%d", reportID, row.RRDID)
+	case row.Number != 0 && reportID != 0:
+		rowKey = fmt.Sprintf("%d|n%d", reportID, row.Number)
+	default:
+		return nil, errors.New("missing rrd_id and row number")
+	}
+
+	date, err := row.Date(location)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.Item
+	for _, c := range wbComponents(row) {
+		kopeks := int(math.Round(math.Abs(c.amount) * 100))
+		if kopeks == 0 {
+			continue
+		}
+
+		itemType := "expense"
+		if c.amount > 0 {
+			itemType = "income"
+		}
+
+		req := dto.ImportItemRequest{
+			Type:     itemType,
+			Amount:   kopeks,
+			Date:     date.Format(time.RFC3339),
+			Category: truncateCategory(c.category),
+		}
+		if err = s.valid.Struct(req); err != nil {
+			return nil, fmt.Errorf("%s: %s", c.name, s.valid.FormatValidationError(err))
+		}
+
+		item, itemErr := newItem(dto.CreateItemRequest{
+			Type:     req.Type,
+			Amount:   req.Amount,
+			Date:     req.Date,
+			Category: req.Category,
+		})
+		if itemErr != nil {
+			return nil, itemErr
+		}
+
+		item.Source = wbReportSource
+		item.ExternalID = rowKey + "|" + c.name
+		item.ID = uuid.NewSHA1(wbNamespace, []byte(item.ExternalID))
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// wbComponents splits a row into money flows. WB reports returns with
+// positive amounts, so the sale, commission and acquiring of a return are
+// reversed. Commission is what WB keeps from the retail amount besides
+// acquiring. Rows without a retail amount, such as compensations, pay out
+// ppvz_for_pay instead.
+func wbComponents(row wbreport.Row) []wbComponent {
+	sign := 1.0
+	if row.IsReturn() {
+		sign = -1
+	}
+
+	var components []wbComponent
+	if row.RetailAmount != 0 {
+		components = append(components,
+			wbComponent{"sale", sign * row.RetailAmount, row.OperName},
+			wbComponent{"commission", -sign * (row.RetailAmount - row.ForPay - row.AcquiringFee), wbCommissionCategory},
+		)
+	} else {
+		components = append(components, wbComponent{"payout", sign * row.ForPay, row.OperName})
+	}
+
+	// Service rows, such as logistics or storage, carry a single cost that
+	// keeps the row's own category.
+	category := func(name string) string {
+		if row.RetailAmount == 0 && row.ForPay == 0 {
+			return row.OperName
+		}
+		return name
+	}
+
+	return append(components,
+		wbComponent{"acquiring", -sign * row.AcquiringFee, wbAcquiringCategory},
+		wbComponent{"logistics", -row.Delivery, category(wbLogisticsCategory)},
+		wbComponent{"storage", -row.StorageFee, category(wbStorageCategory)},
+		wbComponent{"penalty", -row.Penalty, category(wbPenaltyCategory)},
+	)
+}
+
+func truncateCategory(category string) string {
+	if utf8.RuneCountInString(category) <= maxCategoryLength {
+		return category
+	}
+
+	return string([]rune(category)[:maxCategoryLength])
+}
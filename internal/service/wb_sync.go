@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/gookit/slog"
+	"github.com/kstsm/wb-sales-tracker/config"
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/converter"
+	"github.com/kstsm/wb-sales-tracker/internal/dto"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+	"github.com/kstsm/wb-sales-tracker/internal/repository"
+	"github.com/kstsm/wb-sales-tracker/pkg/validator"
+	"github.com/kstsm/wb-sales-tracker/pkg/wbapi"
+)
+
+// wbSyncNamespace seeds the item IDs of synced records, so that a record
+// changed on the WB side overwrites the item created for it before.
+var wbSyncNamespace = uuid.MustParse("0d5f7a3e-9b21-4c68-a4e3-7f2b81c6d950")
+
+const (
+	wbSalesCategory  = "Продажи WB"
+	wbOrdersCategory = "Заказы WB"
+)
+
+// wbSyncDefaultDepth is how far back the first sync goes when no start date
+// is configured. The statistics API keeps about 90 days of records.
+const wbSyncDefaultDepth = 90 * 24 * time.Hour
+
+// wbSyncMaxSkipReasons bounds how many reasons for skipped records are
+// kept in the sync state.
+const wbSyncMaxSkipReasons = 5
+
+// wbSyncBatch is what one request to a source yields: items to write, IDs
+// of items to delete, records that failed validation and the cursor to
+// continue from.
+type wbSyncBatch struct {
+	items        []models.Item
+	deleted      []uuid.UUID
+	skipped      []string
+	lastChangeAt time.Time
+	index        map[uuid.UUID]int
+}
+
+// add queues item, replacing an earlier version of it: a record changed
+// twice within a page is written once, as last seen.
+func (b *wbSyncBatch) add(item models.Item) {
+	if b.index == nil {
+		b.index = make(map[uuid.UUID]int)
+	}
+
+	if i, ok := b.index[item.ID]; ok {
+		b.items[i] = item
+		return
+	}
+
+	b.index[item.ID] = len(b.items)
+	b.items = append(b.items, item)
+}
+
+type wbSyncSource func(w *WBSyncer, ctx context.Context, since time.Time) (*wbSyncBatch, error)
+
+// wbSyncSources fetches the configured WB_SYNC_SOURCES.
+var wbSyncSources = map[string]wbSyncSource{
+	"sales":  (*WBSyncer).fetchSales,
+	"orders": (*WBSyncer).fetchOrders,
+}
+
+type WBSyncManager interface {
+	Run(ctx context.Context)
+	Status(ctx context.Context) (*dto.WBSyncStatusResponse, error)
+}
+
+// WBSyncer polls the WB statistics API and writes the records changed since
+// the stored cursor of each source as items. Items are written before the
+// cursor is saved, so records of an interrupted sync are fetched again and
+// overwrite the same items.
+type WBSyncer struct {
+	repo   repository.ItemManager
+	log    *slog.Logger
+	valid  *validator.Validate
+	client *wbapi.Client
+	cfg    config.WBSync
+
+	mu        sync.Mutex
+	running   bool
+	nextRunAt time.Time
+}
+
+func NewWBSyncer(
+	repo repository.ItemManager,
+	log *slog.Logger,
+	valid *validator.Validate,
+	client *wbapi.Client,
+	cfg config.WBSync,
+) WBSyncManager {
+	return &WBSyncer{
+		repo:   repo,
+		log:    log,
+		valid:  valid,
+		client: client,
+		cfg:    cfg,
+	}
+}
+
+// Run syncs every source right away and then every poll interval until ctx
+// is cancelled. It returns at once if no token is configured.
+func (w *WBSyncer) Run(ctx context.Context) {
+	if w.cfg.Token == "" {
+		w.log.Info("WB sync is disabled: WB_API_TOKEN is not set")
+		return
+	}
+
+	for _, source := range w.cfg.Sources {
+		if _, ok := wbSyncSources[source]; !ok {
+			w.log.Errorf("WB sync: unknown source '%s' is ignored", source)
+		}
+	}
+
+	w.log.Infof("Starting WB sync of %v every %s", w.cfg.Sources, w.cfg.PollInterval)
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.syncAll(ctx)
+
+		w.mu.Lock()
+		w.nextRunAt = time.Now().Add(w.cfg.PollInterval)
+		w.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *WBSyncer) syncAll(ctx context.Context) {
+	w.mu.Lock()
+	w.running = true
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		w.running = false
+		w.mu.Unlock()
+	}()
+
+	for _, source := range w.cfg.Sources {
+		fetch, ok := wbSyncSources[source]
+		if !ok {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := w.syncSource(ctx, source, fetch); err != nil {
+			w.log.Errorf("WB sync of %s: %v", source, err)
+		}
+	}
+}
+
+// syncSource requests one page of changes. A full page is continued on the
+// next run, which keeps the API rate limit of one request per minute.
+func (w *WBSyncer) syncSource(ctx context.Context, source string, fetch wbSyncSource) error {
+	state, err := w.repo.GetSyncState(ctx, source)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &models.SyncState{Source: source, LastChangeAt: w.startDate()}
+	}
+
+	now := time.Now().UTC()
+	state.LastSyncAt = &now
+
+	batch, syncErr := w.apply(ctx, fetch, state)
+	if syncErr != nil {
+		state.LastError = syncErr.Error()
+	} else {
+		state.LastError = ""
+		state.LastSuccessAt = &now
+		state.LastCount = len(batch.items) + len(batch.deleted)
+		state.LastSkipped = len(batch.skipped)
+		state.LastSkippedReason = skipReason(batch.skipped)
+	}
+
+	if err = w.repo.SaveSyncState(ctx, *state); err != nil {
+		return errors.Join(syncErr, err)
+	}
+
+	return syncErr
+}
+
+// apply writes a batch of changes and moves the cursor of state. Records
+// that failed validation are passed as well, since they would fail again on
+// every run; the returned batch lists them.
+func (w *WBSyncer) apply(ctx context.Context, fetch wbSyncSource, state *models.SyncState) (*wbSyncBatch, error) {
+	batch, err := fetch(w, ctx, state.LastChangeAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(batch.items) > 0 {
+		if _, _, err = w.repo.UpsertItems(ctx, batch.items); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range batch.deleted {
+		if err = w.repo.DeleteItem(ctx, id); err != nil && !errors.Is(err, apperrors.ErrItemNotFound) {
+			return nil, err
+		}
+	}
+
+	if batch.lastChangeAt.After(state.LastChangeAt) {
+		state.LastChangeAt = batch.lastChangeAt
+	}
+
+	return batch, nil
+}
+
+// skipReason joins the first reasons for skipped records.
+func skipReason(skipped []string) string {
+	if len(skipped) <= wbSyncMaxSkipReasons {
+		return strings.Join(skipped, "; ")
+	}
+
+	return fmt.Sprintf("%s; and %d more",
+		strings.Join(skipped[:wbSyncMaxSkipReasons], "; "), len(skipped)-wbSyncMaxSkipReasons)
+}
+
+func (w *WBSyncer) startDate() time.Time {
+	if !w.cfg.StartDate.IsZero() {
+		return w.cfg.StartDate
+	}
+
+	return time.Now().Add(-wbSyncDefaultDepth).Truncate(24 * time.Hour)
+}
+
+// fetchSales turns sales into income and returns into expense items for
+// the amount paid to the seller. Returns are told by their saleID rather
+// than by the sign of forPay.
+func (w *WBSyncer) fetchSales(ctx context.Context, since time.Time) (*wbSyncBatch, error) {
+	sales, err := w.client.Sales(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &wbSyncBatch{}
+	for _, sale := range sales {
+		batch.lastChangeAt = latest(batch.lastChangeAt, sale.LastChangeDate.Time)
+
+		externalID := sale.SRID + "|" + sale.SaleID
+		id := uuid.NewSHA1(wbSyncNamespace, []byte("sales|"+externalID))
+		forPay := math.Abs(sale.ForPay)
+		if sale.IsReturn() {
+			forPay = -forPay
+		}
+
+		item, itemErr := w.newItem(id, forPay, sale.Date.Time, sale.Subject, wbSalesCategory)
+		if itemErr != nil {
+			w.log.Warnf("WB sync: skipping sale %s: %v", sale.SaleID, itemErr)
+			batch.skipped = append(batch.skipped, fmt.Sprintf("sale %s: %v", sale.SaleID, itemErr))
+			continue
+		}
+		if item != nil {
+			item.Source, item.ExternalID = "wb_sales", externalID
+			batch.add(*item)
+		}
+	}
+
+	return batch, nil
+}
+
+// fetchOrders turns orders into income items for the price after discounts
+// and deletes the items of cancelled orders.
+func (w *WBSyncer) fetchOrders(ctx context.Context, since time.Time) (*wbSyncBatch, error) {
+	orders, err := w.client.Orders(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &wbSyncBatch{}
+	for _, order := range orders {
+		batch.lastChangeAt = latest(batch.lastChangeAt, order.LastChangeDate.Time)
+
+		id := uuid.NewSHA1(wbSyncNamespace, []byte("orders|"+order.SRID))
+		if order.IsCancel {
+			batch.deleted = append(batch.deleted, id)
+			continue
+		}
+
+		item, itemErr := w.newItem(id, order.PriceWithDisc, order.Date.Time, order.Subject, wbOrdersCategory)
+		if itemErr != nil {
+			w.log.Warnf("WB sync: skipping order %s: %v", order.SRID, itemErr)
+			batch.skipped = append(batch.skipped, fmt.Sprintf("order %s: %v", order.SRID, itemErr))
+			continue
+		}
+		if item != nil {
+			item.Source, item.ExternalID = "wb_orders", order.SRID
+			batch.add(*item)
+		}
+	}
+
+	return batch, nil
+}
+
+// newItem validates a record like any other item. Positive amounts are
+// income and negative ones expenses; a zero amount yields no item. The
+// category is the product subject, or fallback when it is too short.
+// Records without a date, which the API sends as 0001-01-01, are rejected.
+func (w *WBSyncer) newItem(id uuid.UUID, rubles float64, date time.Time, subject, fallback string) (*models.Item, error) {
+	kopeks := int(math.Round(math.Abs(rubles) * 100))
+	if kopeks == 0 {
+		return nil, nil
+	}
+
+	if date.IsZero() {
+		return nil, errors.New("missing date")
+	}
+
+	itemType := "expense"
+	if rubles > 0 {
+		itemType = "income"
+	}
+
+	category := truncateCategory(subject)
+	if utf8.RuneCountInString(category) < 3 {
+		category = fallback
+	}
+
+	req := dto.ImportItemRequest{
+		Type:     itemType,
+		Amount:   kopeks,
+		Date:     date.Format(time.RFC3339),
+		Category: category,
+	}
+	if err := w.valid.Struct(req); err != nil {
+		return nil, errors.New(w.valid.FormatValidationError(err))
+	}
+
+	item, err := newItem(dto.CreateItemRequest{
+		Type:     req.Type,
+		Amount:   req.Amount,
+		Date:     req.Date,
+		Category: req.Category,
+	})
+	if err != nil {
+		return nil, err
+	}
+	item.ID = id
+
+	return &item, nil
+}
+
+// Status returns the stored state of every known configured source along
+// with the state of the poll loop.
+func (w *WBSyncer) Status(ctx context.Context) (*dto.WBSyncStatusResponse, error) {
+	states, err := w.repo.GetSyncStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	resp := &dto.WBSyncStatusResponse{
+		Enabled:  w.cfg.Token != "",
+		Running:  w.running,
+		Interval: w.cfg.PollInterval.String(),
+		Sources:  make([]dto.WBSyncSourceStatus, 0, len(w.cfg.Sources)),
+	}
+	if !w.nextRunAt.IsZero() {
+		resp.NextRunAt = w.nextRunAt.UTC().Format(time.RFC3339)
+	}
+	w.mu.Unlock()
+
+	for _, source := range w.cfg.Sources {
+		if _, ok := wbSyncSources[source]; !ok {
+			continue
+		}
+
+		idx := slices.IndexFunc(states, func(state models.SyncState) bool { return state.Source == source })
+		if idx == -1 {
+			resp.Sources = append(resp.Sources, dto.WBSyncSourceStatus{Source: source})
+			continue
+		}
+		resp.Sources = append(resp.Sources, converter.SyncStateToResponse(&states[idx]))
+	}
+
+	return resp, nil
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+
+	return a
+}
@@ -0,0 +1,400 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gookit/slog"
+	"github.com/kstsm/wb-sales-tracker/config"
+	"github.com/kstsm/wb-sales-tracker/internal/apperrors"
+	"github.com/kstsm/wb-sales-tracker/internal/models"
+	"github.com/kstsm/wb-sales-tracker/internal/repository"
+	"github.com/kstsm/wb-sales-tracker/pkg/validator"
+	"github.com/kstsm/wb-sales-tracker/pkg/wbapi"
+)
+
+// syncRepo keeps items and sync states in memory. Methods the syncer does
+// not use panic through the nil embedded interface.
+type syncRepo struct {
+	repository.ItemManager
+
+	mu     sync.Mutex
+	items  map[uuid.UUID]models.Item
+	states map[string]models.SyncState
+}
+
+func newSyncRepo() *syncRepo {
+	return &syncRepo{
+		items:  make(map[uuid.UUID]models.Item),
+		states: make(map[string]models.SyncState),
+	}
+}
+
+func (r *syncRepo) UpsertItems(_ context.Context, items []models.Item) (int, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var inserted, updated int
+	for _, item := range items {
+		if _, ok := r.items[item.ID]; ok {
+			updated++
+		} else {
+			inserted++
+		}
+		r.items[item.ID] = item
+	}
+
+	return inserted, updated, nil
+}
+
+func (r *syncRepo) DeleteItem(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.items[id]; !ok {
+		return apperrors.ErrItemNotFound
+	}
+	delete(r.items, id)
+
+	return nil
+}
+
+func (r *syncRepo) GetSyncState(_ context.Context, source string) (*models.SyncState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.states[source]
+	if !ok {
+		return nil, nil
+	}
+
+	return &state, nil
+}
+
+func (r *syncRepo) SaveSyncState(_ context.Context, state models.SyncState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.states[state.Source] = state
+
+	return nil
+}
+
+// fakeWB serves the sales and orders methods like the statistics API:
+// records changed at or after dateFrom, at most pageSize of them. A non-zero
+// status is returned instead.
+type fakeWB struct {
+	mu       sync.Mutex
+	sales    []map[string]any
+	orders   []map[string]any
+	pageSize int
+	status   int
+	requests []string
+}
+
+func (f *fakeWB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dateFrom := r.URL.Query().Get("dateFrom")
+	f.requests = append(f.requests, dateFrom)
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+
+	from, err := time.ParseInLocation("2006-01-02T15:04:05", dateFrom, wbapi.Moscow)
+	if err != nil {
+		http.Error(w, "invalid dateFrom", http.StatusBadRequest)
+		return
+	}
+
+	records := f.sales
+	if strings.HasSuffix(r.URL.Path, "/orders") {
+		records = f.orders
+	}
+
+	page := []map[string]any{}
+	for _, record := range records {
+		changed, _ := time.ParseInLocation("2006-01-02T15:04:05", record["lastChangeDate"].(string), wbapi.Moscow)
+		if !changed.Before(from) && len(page) < f.pageSize {
+			page = append(page, record)
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+func newTestSyncer(t *testing.T, api *fakeWB) (*WBSyncer, *syncRepo) {
+	t.Helper()
+
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+
+	repo := newSyncRepo()
+	cfg := config.WBSync{
+		Token:     "test-token",
+		BaseURL:   srv.URL,
+		StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, wbapi.Moscow),
+		Sources:   []string{"sales"},
+	}
+
+	return &WBSyncer{
+		repo:   repo,
+		log:    slog.New(),
+		valid:  validator.NewValidator(),
+		client: wbapi.NewClient(cfg.BaseURL, cfg.Token, time.Second),
+		cfg:    cfg,
+	}, repo
+}
+
+func testSale(saleID, date, changed string, forPay float64) map[string]any {
+	return map[string]any{
+		"date":           date,
+		"lastChangeDate": changed,
+		"srid":           "srid-" + saleID,
+		"saleID":         saleID,
+		"subject":        "Платья",
+		"forPay":         forPay,
+	}
+}
+
+func testOrder(srid, date, changed string, price float64, cancelled bool) map[string]any {
+	return map[string]any{
+		"date":           date,
+		"lastChangeDate": changed,
+		"srid":           srid,
+		"subject":        "Платья",
+		"priceWithDisc":  price,
+		"isCancel":       cancelled,
+	}
+}
+
+func TestSyncSourceAdvancesCursor(t *testing.T) {
+	api := &fakeWB{
+		pageSize: 2,
+		sales: []map[string]any{
+			testSale("S1", "2025-01-02T10:00:00", "2025-01-02T10:05:00", 100),
+			testSale("S2", "2025-01-03T10:00:00", "2025-01-03T10:05:00", 200),
+			testSale("R3", "2025-01-04T10:00:00", "2025-01-04T10:05:00", -50),
+		},
+	}
+	w, repo := newTestSyncer(t, api)
+	ctx := context.Background()
+
+	if err := w.syncSource(ctx, "sales", (*WBSyncer).fetchSales); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	state := repo.states["sales"]
+	if want := time.Date(2025, 1, 3, 7, 5, 0, 0, time.UTC); !state.LastChangeAt.Equal(want) {
+		t.Fatalf("cursor after the first page = %s, want %s", state.LastChangeAt, want)
+	}
+	if state.LastCount != 2 || len(repo.items) != 2 {
+		t.Fatalf("first sync wrote %d items, state counts %d, want 2", len(repo.items), state.LastCount)
+	}
+
+	if err := w.syncSource(ctx, "sales", (*WBSyncer).fetchSales); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+
+	state = repo.states["sales"]
+	if want := time.Date(2025, 1, 4, 7, 5, 0, 0, time.UTC); !state.LastChangeAt.Equal(want) {
+		t.Fatalf("cursor after the second page = %s, want %s", state.LastChangeAt, want)
+	}
+	if len(repo.items) != 3 {
+		t.Fatalf("got %d items after the second page, want 3", len(repo.items))
+	}
+
+	if got := api.requests; len(got) != 2 || got[0] != "2025-01-01T00:00:00" || got[1] != "2025-01-03T10:05:00" {
+		t.Fatalf("dateFrom of the requests = %v", got)
+	}
+
+	externalIDs := map[string]bool{"srid-S1|S1": true, "srid-S2|S2": true, "srid-R3|R3": true}
+
+	var incomes, expenses int
+	for _, item := range repo.items {
+		if item.Source != "wb_sales" || !externalIDs[item.ExternalID] {
+			t.Errorf("item %s has source %q and external id %q", item.ID, item.Source, item.ExternalID)
+		}
+		switch item.Type {
+		case "income":
+			incomes++
+		case "expense":
+			expenses++
+			if item.Amount != 5000 {
+				t.Errorf("return amount = %d kopeks, want 5000", item.Amount)
+			}
+		}
+	}
+	if incomes != 2 || expenses != 1 {
+		t.Fatalf("got %d income and %d expense items, want 2 and 1", incomes, expenses)
+	}
+}
+
+func TestSyncOrdersDeletesCancelled(t *testing.T) {
+	api := &fakeWB{
+		pageSize: 10,
+		orders: []map[string]any{
+			testOrder("O1", "2025-01-02T10:00:00", "2025-01-02T10:05:00", 1000, false),
+			testOrder("O2", "2025-01-03T10:00:00", "2025-01-03T10:05:00", 1200, false),
+			// Cancelled before it was ever synced: there is nothing to delete.
+			testOrder("O3", "2025-01-03T11:00:00", "2025-01-03T11:05:00", 900, true),
+		},
+	}
+	w, repo := newTestSyncer(t, api)
+	ctx := context.Background()
+
+	if err := w.syncSource(ctx, "orders", (*WBSyncer).fetchOrders); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	if state := repo.states["orders"]; state.LastError != "" || state.LastCount != 3 {
+		t.Fatalf("state after the first sync: %+v", state)
+	}
+	if len(repo.items) != 2 {
+		t.Fatalf("got %d items after the first sync, want 2", len(repo.items))
+	}
+	for _, item := range repo.items {
+		if item.Source != "wb_orders" || item.Type != "income" {
+			t.Errorf("item %s has source %q and type %q", item.ID, item.Source, item.Type)
+		}
+		if item.ExternalID == "O2" && item.Amount != 120000 {
+			t.Errorf("order O2 amount = %d kopeks, want 120000", item.Amount)
+		}
+	}
+
+	// O1 is cancelled later, and O4 is placed and cancelled within the same
+	// page: the deletes of a batch run after its upserts.
+	api.orders = []map[string]any{
+		testOrder("O1", "2025-01-02T10:00:00", "2025-01-05T09:00:00", 1000, true),
+		testOrder("O2", "2025-01-03T10:00:00", "2025-01-03T10:05:00", 1200, false),
+		testOrder("O4", "2025-01-05T10:00:00", "2025-01-05T10:05:00", 500, false),
+		testOrder("O4", "2025-01-05T10:00:00", "2025-01-05T10:30:00", 500, true),
+	}
+
+	if err := w.syncSource(ctx, "orders", (*WBSyncer).fetchOrders); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+
+	if len(repo.items) != 1 {
+		t.Fatalf("got %d items after the cancellations, want only O2", len(repo.items))
+	}
+	for _, item := range repo.items {
+		if item.ExternalID != "O2" {
+			t.Fatalf("item %s of order %q was not deleted", item.ID, item.ExternalID)
+		}
+	}
+
+	if want := time.Date(2025, 1, 5, 7, 30, 0, 0, time.UTC); !repo.states["orders"].LastChangeAt.Equal(want) {
+		t.Fatalf("cursor = %s, want %s", repo.states["orders"].LastChangeAt, want)
+	}
+}
+
+func TestSyncSourceIsIdempotent(t *testing.T) {
+	api := &fakeWB{
+		pageSize: 10,
+		sales: []map[string]any{
+			testSale("S1", "2025-01-02T10:00:00", "2025-01-02T10:05:00", 100),
+			testSale("S2", "2025-01-03T10:00:00", "2025-01-03T10:05:00", 200),
+		},
+	}
+	w, repo := newTestSyncer(t, api)
+	ctx := context.Background()
+
+	if err := w.syncSource(ctx, "sales", (*WBSyncer).fetchSales); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	before := make(map[uuid.UUID]models.Item, len(repo.items))
+	for id, item := range repo.items {
+		before[id] = item
+	}
+
+	// The last record is returned again, since dateFrom is inclusive, and a
+	// lost cursor makes the whole history come back.
+	for range 2 {
+		if err := w.syncSource(ctx, "sales", (*WBSyncer).fetchSales); err != nil {
+			t.Fatalf("repeated sync: %v", err)
+		}
+		delete(repo.states, "sales")
+	}
+
+	if len(repo.items) != len(before) {
+		t.Fatalf("got %d items after re-sync, want %d", len(repo.items), len(before))
+	}
+	for id, item := range before {
+		got, ok := repo.items[id]
+		if !ok || got.Amount != item.Amount || got.ExternalID != item.ExternalID {
+			t.Fatalf("item %s changed on re-sync: %+v, was %+v", id, got, item)
+		}
+	}
+}
+
+func TestSyncSourceReportsSkippedRecords(t *testing.T) {
+	api := &fakeWB{
+		pageSize: 10,
+		sales: []map[string]any{
+			testSale("S1", "0001-01-01T00:00:00", "2025-01-02T10:05:00", 100),
+			testSale("S2", "2025-01-03T10:00:00", "2025-01-03T10:05:00", 200),
+		},
+	}
+	w, repo := newTestSyncer(t, api)
+
+	if err := w.syncSource(context.Background(), "sales", (*WBSyncer).fetchSales); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	state := repo.states["sales"]
+	if state.LastSkipped != 1 || state.LastSkippedReason != "sale S1: missing date" {
+		t.Fatalf("skipped = %d (%q), want 1 with the reason", state.LastSkipped, state.LastSkippedReason)
+	}
+	if state.LastCount != 1 || len(repo.items) != 1 {
+		t.Fatalf("got %d items, state counts %d, want 1", len(repo.items), state.LastCount)
+	}
+	if state.LastError != "" {
+		t.Fatalf("last error = %q, want none", state.LastError)
+	}
+}
+
+func TestSyncSourceStoresAPIErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   error
+	}{
+		{"unauthorized", http.StatusUnauthorized, wbapi.ErrUnauthorized},
+		{"rate limited", http.StatusTooManyRequests, wbapi.ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeWB{pageSize: 10, status: tt.status}
+			w, repo := newTestSyncer(t, api)
+
+			err := w.syncSource(context.Background(), "sales", (*WBSyncer).fetchSales)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+
+			state := repo.states["sales"]
+			if state.LastError != tt.want.Error() {
+				t.Errorf("last error = %q, want %q", state.LastError, tt.want.Error())
+			}
+			if state.LastSuccessAt != nil || state.LastSyncAt == nil {
+				t.Errorf("last success = %v, last sync = %v, want only the sync time", state.LastSuccessAt, state.LastSyncAt)
+			}
+			if !state.LastChangeAt.Equal(w.cfg.StartDate) {
+				t.Errorf("cursor = %s, want it to stay at %s", state.LastChangeAt, w.cfg.StartDate)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS wb_sync_state
+(
+    source          VARCHAR(16) PRIMARY KEY,
+    last_change_at  TIMESTAMPTZ NOT NULL,
+    last_sync_at    TIMESTAMPTZ,
+    last_success_at TIMESTAMPTZ,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    last_count      INT         NOT NULL DEFAULT 0,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS wb_sync_state;
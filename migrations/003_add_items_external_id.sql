@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE items
+    ADD COLUMN IF NOT EXISTS source      VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS external_id VARCHAR(128),
+    ADD CONSTRAINT items_source_external_id_check CHECK ((source IS NULL) = (external_id IS NULL)),
+    ADD CONSTRAINT items_source_external_id_key UNIQUE (source, external_id);
+
+CREATE INDEX IF NOT EXISTS idx_items_amount_category_date ON items (amount, category, date);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_items_amount_category_date;
+
+ALTER TABLE items
+    DROP CONSTRAINT IF EXISTS items_source_external_id_key,
+    DROP CONSTRAINT IF EXISTS items_source_external_id_check,
+    DROP COLUMN IF EXISTS external_id,
+    DROP COLUMN IF EXISTS source;
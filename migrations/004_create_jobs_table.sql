@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS jobs
+(
+    id          UUID PRIMARY KEY,
+    type        VARCHAR(16) NOT NULL,
+    status      VARCHAR(16) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled')),
+    params      JSONB       NOT NULL DEFAULT '{}',
+    file        BYTEA,
+    total_bytes BIGINT      NOT NULL,
+    read_bytes  BIGINT      NOT NULL DEFAULT 0,
+    attempts    INT         NOT NULL DEFAULT 0,
+    result      JSONB,
+    error       TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at  TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status_created_at ON jobs (status, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS jobs;
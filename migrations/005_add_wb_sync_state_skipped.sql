@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE wb_sync_state
+    ADD COLUMN IF NOT EXISTS last_skipped        INT  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_skipped_reason TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE wb_sync_state
+    DROP COLUMN IF EXISTS last_skipped_reason,
+    DROP COLUMN IF EXISTS last_skipped;
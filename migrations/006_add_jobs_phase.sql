@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS phase          VARCHAR(16) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS processed_rows INT         NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS total_rows     INT         NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS total_rows,
+    DROP COLUMN IF EXISTS processed_rows,
+    DROP COLUMN IF EXISTS phase;
@@ -0,0 +1,65 @@
+// Package bankfile reads bank statement files into a common list of
+// transactions.
+package bankfile
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFile = errors.New("invalid statement file")
+
+// Statement is the result of parsing a statement file. Records that are
+// present but cannot be turned into a transaction are listed in Skipped
+// instead of failing the whole file.
+type Statement struct {
+	Transactions []Transaction
+	Skipped      []Skipped
+}
+
+// Transaction is one operation on our account.
+type Transaction struct {
+	// Line is where the record starts in the file, for error reports.
+	Line int
+	// Account is our account the operation belongs to, if the file says.
+	Account string
+	// ID identifies the operation within the account, such as a document
+	// number or an OFX FITID.
+	ID   string
+	Date time.Time
+	// Amount is the unsigned decimal amount with a '.' separator, as
+	// written in the file.
+	Amount string
+	// Currency is the ISO 4217 code of Amount, empty when the file does not
+	// say, as in 1C and QIF files.
+	Currency     string
+	Incoming     bool
+	Counterparty string
+	Purpose      string
+	// Category is set when the file itself assigns one, as QIF does.
+	Category string
+}
+
+// Skipped is a record that was not turned into a transaction.
+type Skipped struct {
+	Line   int
+	Reason string
+}
+
+func (s *Statement) skip(line int, reason string) {
+	s.Skipped = append(s.Skipped, Skipped{Line: line, Reason: reason})
+}
+
+// normalizeAmount removes digit grouping and uses '.' as the decimal
+// separator.
+func normalizeAmount(s string) string {
+	s = strings.NewReplacer(" ", "", "\u00a0", "", "'", "").Replace(strings.TrimSpace(s))
+
+	if strings.Contains(s, ",") && strings.Contains(s, ".") {
+		// 1,234.56: the comma groups thousands.
+		s = strings.ReplaceAll(s, ",", "")
+	}
+
+	return strings.ReplaceAll(s, ",", ".")
+}
@@ -0,0 +1,203 @@
+package bankfile
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// camtEntry is the part of a camt.053 Ntry element that is needed for a
+// transaction. Element names are matched without namespace, so the 001.02
+// to 001.08 schema versions are all read.
+type camtEntry struct {
+	Ref    string `xml:"NtryRef"`
+	Amount struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	Indicator string `xml:"CdtDbtInd"`
+	// Sts is a code up to 001.04 and has a Cd child since 001.05.
+	Status struct {
+		Text string `xml:",chardata"`
+		Code string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookingDate string `xml:"BookgDt>Dt"`
+	BookingTime string `xml:"BookgDt>DtTm"`
+	ValueDate   string `xml:"ValDt>Dt"`
+	ServicerRef string `xml:"AcctSvcrRef"`
+	Info        string `xml:"AddtlNtryInf"`
+	Details     []struct {
+		EndToEndID   string   `xml:"Refs>EndToEndId"`
+		Unstructured []string `xml:"RmtInf>Ustrd"`
+		Debtor       string   `xml:"RltdPties>Dbtr>Nm"`
+		DebtorParty  string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+		Creditor     string   `xml:"RltdPties>Cdtr>Nm"`
+		CreditorPty  string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+type camtAccount struct {
+	IBAN  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+}
+
+// ParseCAMT053 reads the booked entries of an ISO 20022 camt.053 bank to
+// customer statement. CdtDbtInd gives the direction of the booking, also
+// for reversals: a reversed debit is booked as CRDT with RvslInd set. The
+// booking date gives the date; dates without a zone are read in loc.
+// The currency of an entry is the Ccy attribute of its amount.
+// Entries that are not booked are skipped.
+func ParseCAMT053(r io.Reader, loc *time.Location) (*Statement, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(label) {
+		case "windows-1251", "cp1251":
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		case "windows-1252", "cp1252", "iso-8859-1", "latin1":
+			return charmap.Windows1252.NewDecoder().Reader(input), nil
+		}
+		return nil, fmt.Errorf("unsupported charset '%s'", label)
+	}
+
+	var (
+		stmt       Statement
+		account    string
+		statements int
+	)
+
+	for {
+		line, _ := decoder.InputPos()
+
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "Stmt":
+			statements++
+			account = ""
+		case "Acct":
+			var acct camtAccount
+			if err = decoder.DecodeElement(&acct, &start); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+			}
+			account = acct.IBAN
+			if account == "" {
+				account = acct.Other
+			}
+		case "Ntry":
+			var entry camtEntry
+			if err = decoder.DecodeElement(&entry, &start); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+			}
+			stmt.addCAMTEntry(line, account, entry, loc)
+		}
+	}
+
+	if statements == 0 {
+		return nil, fmt.Errorf("%w: no camt.053 Stmt element", ErrInvalidFile)
+	}
+
+	return &stmt, nil
+}
+
+func (s *Statement) addCAMTEntry(line int, account string, entry camtEntry, loc *time.Location) {
+	status := firstNonEmpty(entry.Status.Code, entry.Status.Text)
+	if status != "" && status != "BOOK" {
+		s.skip(line, fmt.Sprintf("entry is not booked (status %s)", status))
+		return
+	}
+
+	var incoming bool
+	switch strings.TrimSpace(entry.Indicator) {
+	case "CRDT":
+		incoming = true
+	case "DBIT":
+	default:
+		s.skip(line, fmt.Sprintf("invalid CdtDbtInd '%s'", entry.Indicator))
+		return
+	}
+
+	date, err := parseCAMTDate(entry, loc)
+	if err != nil {
+		s.skip(line, err.Error())
+		return
+	}
+
+	tx := Transaction{
+		Line:     line,
+		Account:  account,
+		ID:       firstNonEmpty(entry.ServicerRef, entry.Ref),
+		Date:     date,
+		Amount:   normalizeAmount(entry.Amount.Value),
+		Currency: strings.ToUpper(strings.TrimSpace(entry.Amount.Currency)),
+		Incoming: incoming,
+		Purpose:  strings.TrimSpace(entry.Info),
+	}
+
+	if len(entry.Details) > 0 {
+		details := entry.Details[0]
+		tx.ID = firstNonEmpty(tx.ID, details.EndToEndID)
+
+		if purpose := strings.TrimSpace(strings.Join(details.Unstructured, " ")); purpose != "" {
+			tx.Purpose = purpose
+		}
+
+		if incoming {
+			tx.Counterparty = firstNonEmpty(details.Debtor, details.DebtorParty)
+		} else {
+			tx.Counterparty = firstNonEmpty(details.Creditor, details.CreditorPty)
+		}
+	}
+
+	s.Transactions = append(s.Transactions, tx)
+}
+
+func parseCAMTDate(entry camtEntry, loc *time.Location) (time.Time, error) {
+	if dt := strings.TrimSpace(entry.BookingTime); dt != "" {
+		if t, err := time.Parse(time.RFC3339, dt); err == nil {
+			return t, nil
+		}
+		if t, err := time.ParseInLocation("2006-01-02T15:04:05", dt, loc); err == nil {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("invalid booking date '%s'", dt)
+	}
+
+	d := firstNonEmpty(entry.BookingDate, entry.ValueDate)
+	if d == "" {
+		return time.Time{}, errors.New("missing booking date")
+	}
+
+	// Dates may carry a zone offset, as in 2025-01-02+01:00.
+	t, err := time.ParseInLocation(time.DateOnly, d[:min(len(d), len(time.DateOnly))], loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid booking date '%s'", d)
+	}
+
+	return t, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
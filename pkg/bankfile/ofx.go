@@ -0,0 +1,158 @@
+package bankfile
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+var (
+	ofxTransactionPattern = regexp.MustCompile(`(?is)<STMTTRN>(.*?)</STMTTRN>`)
+	ofxAccountPattern     = regexp.MustCompile(`(?is)<ACCTID>([^<\r\n]*)`)
+	ofxCurrencyPattern    = regexp.MustCompile(`(?is)<CURDEF>([^<\r\n]*)`)
+	ofxCharsetPattern     = regexp.MustCompile(`(?i)CHARSET:\s*(\d+)`)
+)
+
+// ParseOFX reads the bank and credit card transactions of an OFX file,
+// either SGML (1.x) with unclosed elements or XML (2.x). The sign of TRNAMT
+// gives the direction and DTPOSTED the date; dates without a zone are read
+// in loc. Files that are not UTF-8 are decoded by their CHARSET header.
+// Amounts are in CURDEF unless a transaction has its own CURRENCY.
+func ParseOFX(r io.Reader, loc *time.Location) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	if !utf8.Valid(data) {
+		decoder := charmap.Windows1252.NewDecoder()
+		if m := ofxCharsetPattern.FindSubmatch(data); m != nil && string(m[1]) == "1251" {
+			decoder = charmap.Windows1251.NewDecoder()
+		}
+		if data, err = decoder.Bytes(data); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+	}
+
+	if !bytes.Contains(bytes.ToUpper(data), []byte("<OFX>")) {
+		return nil, fmt.Errorf("%w: missing <OFX> element", ErrInvalidFile)
+	}
+
+	var account string
+	if m := ofxAccountPattern.FindSubmatch(data); m != nil {
+		account = strings.TrimSpace(html.UnescapeString(string(m[1])))
+	}
+
+	var currency string
+	if m := ofxCurrencyPattern.FindSubmatch(data); m != nil {
+		currency = strings.ToUpper(strings.TrimSpace(string(m[1])))
+	}
+
+	var stmt Statement
+	for _, m := range ofxTransactionPattern.FindAllSubmatchIndex(data, -1) {
+		line := bytes.Count(data[:m[0]], []byte("\n")) + 1
+		fields := ofxFields(string(data[m[2]:m[3]]))
+
+		amountStr := normalizeAmount(fields["TRNAMT"])
+		amount, parseErr := strconv.ParseFloat(amountStr, 64)
+		if parseErr != nil {
+			stmt.skip(line, fmt.Sprintf("invalid amount '%s'", fields["TRNAMT"]))
+			continue
+		}
+		if amount == 0 {
+			stmt.skip(line, "zero amount")
+			continue
+		}
+
+		date, parseErr := parseOFXTime(fields["DTPOSTED"], loc)
+		if parseErr != nil {
+			stmt.skip(line, fmt.Sprintf("invalid date '%s'", fields["DTPOSTED"]))
+			continue
+		}
+
+		// ORIGCURRENCY only tells what the amount was converted from, while
+		// CURRENCY means the amount is not in CURDEF.
+		txCurrency := currency
+		if _, ok := fields["CURRENCY"]; ok && fields["CURSYM"] != "" {
+			txCurrency = strings.ToUpper(fields["CURSYM"])
+		}
+
+		memo := fields["MEMO"]
+		if memo == "" {
+			memo = fields["NAME"]
+		}
+
+		stmt.Transactions = append(stmt.Transactions, Transaction{
+			Line:         line,
+			Account:      account,
+			ID:           fields["FITID"],
+			Date:         date,
+			Amount:       strings.TrimPrefix(strings.TrimPrefix(amountStr, "-"), "+"),
+			Currency:     txCurrency,
+			Incoming:     amount > 0,
+			Counterparty: fields["NAME"],
+			Purpose:      memo,
+		})
+	}
+
+	return &stmt, nil
+}
+
+// ofxFields collects the leaf elements of a STMTTRN block. SGML leaves are
+// not closed, so a value runs up to the next tag.
+func ofxFields(block string) map[string]string {
+	fields := make(map[string]string)
+
+	for part := range strings.SplitSeq(block, "<") {
+		name, value, ok := strings.Cut(part, ">")
+		if !ok || name == "" || strings.HasPrefix(name, "/") {
+			continue
+		}
+
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if _, seen := fields[name]; !seen {
+			fields[name] = strings.TrimSpace(html.UnescapeString(value))
+		}
+	}
+
+	return fields
+}
+
+// parseOFXTime reads YYYYMMDD[HHMMSS[.XXX]][[gmt offset[:tz name]]].
+func parseOFXTime(s string, loc *time.Location) (time.Time, error) {
+	value, zone, hasZone := strings.Cut(strings.TrimSpace(s), "[")
+	if dot := strings.IndexByte(value, '.'); dot != -1 {
+		value = value[:dot]
+	}
+
+	var layout string
+	switch len(value) {
+	case len("20060102"):
+		layout = "20060102"
+	case len("200601021504"):
+		layout = "200601021504"
+	case len("20060102150405"):
+		layout = "20060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("invalid OFX date '%s'", s)
+	}
+
+	if hasZone {
+		offsetStr, _, _ := strings.Cut(strings.TrimSuffix(zone, "]"), ":")
+		hours, err := strconv.ParseFloat(offsetStr, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid OFX time zone '%s'", s)
+		}
+		loc = time.FixedZone("", int(hours*3600))
+	}
+
+	return time.ParseInLocation(layout, value, loc)
+}
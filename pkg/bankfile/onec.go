@@ -0,0 +1,155 @@
+package bankfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	oneCSignature  = "1CClientBankExchange"
+	oneCDateLayout = "02.01.2006"
+	utf8BOM        = "\ufeff"
+)
+
+// ParseOneC reads a 1CClientBankExchange file in windows-1251 or UTF-8.
+// Documents are incoming when the payee account is one of the statement
+// accounts and outgoing when the payer account is; files without account
+// lists fall back to ДатаПоступило and ДатаСписано. Dates are read in loc.
+func ParseOneC(r io.Reader, loc *time.Location) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	if !utf8.Valid(data) {
+		if data, err = charmap.Windows1251.NewDecoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+	}
+	data = bytes.TrimPrefix(data, []byte(utf8BOM))
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+
+	var (
+		stmt     Statement
+		accounts []string
+		doc      map[string]string
+		docLine  int
+		line     int
+	)
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if line == 1 {
+			if text != oneCSignature {
+				return nil, fmt.Errorf("%w: missing %s signature", ErrInvalidFile, oneCSignature)
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(text, "=")
+
+		switch {
+		case key == "СекцияДокумент":
+			if doc != nil {
+				stmt.skip(docLine, "document is not terminated by КонецДокумента")
+			}
+			doc, docLine = map[string]string{}, line
+		case key == "КонецДокумента" && doc != nil:
+			stmt.addOneCDocument(docLine, doc, accounts, loc)
+			doc = nil
+		case doc != nil:
+			doc[key] = value
+		case key == "РасчСчет" && value != "":
+			if !slices.Contains(accounts, value) {
+				accounts = append(accounts, value)
+			}
+		case key == "КонецФайла":
+			return &stmt, nil
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	if line == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidFile)
+	}
+
+	if doc != nil {
+		stmt.skip(docLine, "document is not terminated by КонецДокумента")
+	}
+
+	return &stmt, nil
+}
+
+func (s *Statement) addOneCDocument(line int, doc map[string]string, accounts []string, loc *time.Location) {
+	field := func(names ...string) string {
+		for _, name := range names {
+			if v := strings.TrimSpace(doc[name]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	payerAccount := field("ПлательщикСчет", "ПлательщикРасчСчет")
+	payeeAccount := field("ПолучательСчет", "ПолучательРасчСчет")
+	payerOurs := slices.Contains(accounts, payerAccount)
+	payeeOurs := slices.Contains(accounts, payeeAccount)
+
+	tx := Transaction{
+		Line:   line,
+		ID:     field("Номер"),
+		Amount: normalizeAmount(field("Сумма")),
+	}
+
+	var dateStr string
+	switch {
+	case payerOurs && payeeOurs:
+		s.skip(line, "transfer between own accounts")
+		return
+	case payeeOurs, !payerOurs && field("ДатаПоступило") != "" && field("ДатаСписано") == "":
+		tx.Incoming = true
+		tx.Account = payeeAccount
+		tx.Counterparty = field("Плательщик1", "Плательщик")
+		dateStr = field("ДатаПоступило", "Дата")
+	case payerOurs, field("ДатаСписано") != "" && field("ДатаПоступило") == "":
+		tx.Account = payerAccount
+		tx.Counterparty = field("Получатель1", "Получатель")
+		dateStr = field("ДатаСписано", "Дата")
+	default:
+		s.skip(line, "cannot tell whether the document is incoming or outgoing")
+		return
+	}
+
+	date, err := time.ParseInLocation(oneCDateLayout, dateStr, loc)
+	if err != nil {
+		s.skip(line, fmt.Sprintf("invalid date '%s'", dateStr))
+		return
+	}
+	tx.Date = date
+
+	// НазначениеПлатежа1..6 continue the purpose in files of older banks.
+	purpose := []string{field("НазначениеПлатежа")}
+	for i := 1; i <= 6; i++ {
+		if part := field(fmt.Sprintf("НазначениеПлатежа%d", i)); part != "" {
+			purpose = append(purpose, part)
+		}
+	}
+	tx.Purpose = strings.TrimSpace(strings.Join(purpose, " "))
+
+	s.Transactions = append(s.Transactions, tx)
+}
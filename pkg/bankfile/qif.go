@@ -0,0 +1,188 @@
+package bankfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// ParseQIF reads the entries of the bank, cash and credit card sections of
+// a QIF file. The sign of T gives the direction. Dates are month first
+// (12/31/2025, 12/31'25), or day first when separated by dots (31.12.2025),
+// and are read in loc. QIF has no transaction IDs, so identical entries are
+// told apart by their order in the file.
+func ParseQIF(r io.Reader, loc *time.Location) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	if !utf8.Valid(data) {
+		if data, err = charmap.Windows1251.NewDecoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+	}
+	data = bytes.TrimPrefix(data, []byte(utf8BOM))
+
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("!")) {
+		return nil, fmt.Errorf("%w: missing !Type header", ErrInvalidFile)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+
+	var (
+		stmt        Statement
+		entry       = map[byte]string{}
+		entryLine   int
+		line        int
+		inBank      bool
+		occurrences = map[string]int{}
+	)
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), " \t")
+		if text == "" {
+			continue
+		}
+
+		if text[0] == '!' {
+			header := strings.ToLower(strings.TrimSpace(text))
+			inBank = header == "!type:bank" || header == "!type:cash" || header == "!type:ccard"
+			continue
+		}
+
+		if text[0] != '^' {
+			if len(entry) == 0 {
+				entryLine = line
+			}
+			if _, seen := entry[text[0]]; !seen {
+				entry[text[0]] = strings.TrimSpace(text[1:])
+			}
+			continue
+		}
+
+		if inBank && len(entry) > 0 {
+			stmt.addQIFEntry(entryLine, entry, loc, occurrences)
+		}
+		entry = map[byte]string{}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	if inBank && len(entry) > 0 {
+		stmt.skip(entryLine, "entry is not terminated by ^")
+	}
+
+	return &stmt, nil
+}
+
+func (s *Statement) addQIFEntry(line int, entry map[byte]string, loc *time.Location, occurrences map[string]int) {
+	amountField := entry['T']
+	if amountField == "" {
+		amountField = entry['U']
+	}
+
+	amountStr := normalizeAmount(amountField)
+	amount, err := strconv.ParseFloat(amountStr, 64)
+	if err != nil {
+		s.skip(line, fmt.Sprintf("invalid amount '%s'", amountField))
+		return
+	}
+	if amount == 0 {
+		s.skip(line, "zero amount")
+		return
+	}
+
+	date, err := parseQIFDate(entry['D'], loc)
+	if err != nil {
+		s.skip(line, err.Error())
+		return
+	}
+
+	memo := entry['M']
+	if memo == "" {
+		memo = entry['P']
+	}
+
+	// A category in brackets is a transfer to another account, not a
+	// category.
+	category := entry['L']
+	if strings.HasPrefix(category, "[") {
+		category = ""
+	}
+
+	id := entry['N']
+	if id == "" {
+		key := strings.Join([]string{entry['D'], amountStr, entry['P'], entry['M']}, "|")
+		occurrences[key]++
+		id = fmt.Sprintf("%s#%d", key, occurrences[key])
+	}
+
+	s.Transactions = append(s.Transactions, Transaction{
+		Line:         line,
+		ID:           id,
+		Date:         date,
+		Amount:       strings.TrimPrefix(strings.TrimPrefix(amountStr, "-"), "+"),
+		Incoming:     amount > 0,
+		Counterparty: entry['P'],
+		Purpose:      memo,
+		Category:     category,
+	})
+}
+
+func parseQIFDate(s string, loc *time.Location) (time.Time, error) {
+	invalid := fmt.Errorf("invalid date '%s'", s)
+
+	value := strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	dayFirst := strings.Contains(value, ".")
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '\''
+	})
+	if len(parts) != 3 {
+		return time.Time{}, invalid
+	}
+
+	nums := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		nums[i] = n
+	}
+
+	var year, month, day int
+	switch {
+	case len(parts[0]) == 4:
+		year, month, day = nums[0], nums[1], nums[2]
+	case dayFirst:
+		day, month, year = nums[0], nums[1], nums[2]
+	default:
+		month, day, year = nums[0], nums[1], nums[2]
+	}
+
+	if len(parts[2]) <= 2 && len(parts[0]) != 4 {
+		year += 2000
+		if year > time.Now().Year()+1 {
+			year -= 100
+		}
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+	if date.Year() != year || int(date.Month()) != month || date.Day() != day {
+		return time.Time{}, invalid
+	}
+
+	return date, nil
+}
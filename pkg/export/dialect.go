@@ -0,0 +1,166 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const utf8BOM = "\ufeff"
+
+// Dialect describes how a CSV file is laid out for a particular consumer.
+// Zero values keep the encoding/csv and Exporter defaults: two-decimal floats
+// and RFC3339 times.
+type Dialect struct {
+	Delimiter        rune
+	BOM              bool
+	DecimalSeparator string
+	// DateLayout is used for times, and for time fields whose csv tag has no
+	// format, in Location when it is set.
+	DateLayout string
+	Location   *time.Location
+	// HeaderLanguage selects localized column names. Translations are
+	// supplied by the caller.
+	HeaderLanguage string
+}
+
+var dialects = map[string]Dialect{
+	"default": {},
+	// excel-ru opens correctly in Excel with Russian regional settings.
+	"excel-ru": {
+		Delimiter:        ';',
+		BOM:              true,
+		DecimalSeparator: ",",
+		DateLayout:       "02.01.2006 15:04:05",
+		HeaderLanguage:   "ru",
+	},
+}
+
+// LookupDialect returns a named dialect preset.
+func LookupDialect(name string) (Dialect, bool) {
+	d, ok := dialects[name]
+	return d, ok
+}
+
+// SniffDialect returns the preset a CSV file was most likely written with,
+// judging by the delimiter that occurs most often in its header line.
+func SniffDialect(header string) Dialect {
+	best, bestCount := dialects["default"], strings.Count(header, ",")
+	for _, d := range dialects {
+		if d.Delimiter == 0 {
+			continue
+		}
+		if count := strings.Count(header, string(d.Delimiter)); count > bestCount {
+			best, bestCount = d, count
+		}
+	}
+
+	return best
+}
+
+// ValidDelimiter reports whether r can separate fields by the rules of
+// encoding/csv: a valid character other than a quote or a line break.
+func ValidDelimiter(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
+
+// NewCSVWriter writes the byte order mark if the dialect needs one and
+// returns a csv.Writer using the dialect delimiter. An invalid delimiter is
+// reported before anything is written.
+func (d Dialect) NewCSVWriter(w io.Writer) (*csv.Writer, error) {
+	if d.Delimiter != 0 && !ValidDelimiter(d.Delimiter) {
+		return nil, fmt.Errorf("invalid delimiter %q", d.Delimiter)
+	}
+
+	if d.BOM {
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return nil, fmt.Errorf("write BOM: %w", err)
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if d.Delimiter != 0 {
+		writer.Comma = d.Delimiter
+	}
+
+	return writer, nil
+}
+
+// FormatFloat formats v with two decimals and the dialect decimal separator.
+func (d Dialect) FormatFloat(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	if d.DecimalSeparator != "" {
+		s = strings.Replace(s, ".", d.DecimalSeparator, 1)
+	}
+
+	return s
+}
+
+// FormatTime formats t with the dialect layout in its location, or as
+// RFC3339 in UTC by default.
+func (d Dialect) FormatTime(t time.Time) string {
+	t = t.UTC()
+	if d.Location != nil {
+		t = t.In(d.Location)
+	}
+
+	layout := d.DateLayout
+	if layout == "" {
+		layout = time.RFC3339
+	}
+
+	return t.Format(layout)
+}
+
+// ParseTime is the inverse of FormatTime: it parses s with the dialect
+// layout in its location, UTC by default, or as RFC3339.
+func (d Dialect) ParseTime(s string) (time.Time, error) {
+	if d.DateLayout == "" {
+		return time.Parse(time.RFC3339, s)
+	}
+
+	location := d.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	return time.ParseInLocation(d.DateLayout, s, location)
+}
+
+// WithDialect returns a copy of the exporter whose time and float formatters
+// follow the dialect date layout and decimal separator.
+func (e *Exporter) WithDialect(d Dialect) *Exporter {
+	clone := &Exporter{formatters: maps.Clone(e.formatters)}
+
+	if d.DateLayout != "" || d.Location != nil {
+		base := e.formatters[reflect.TypeFor[time.Time]()]
+		RegisterFormatter(clone, func(v time.Time, format string) (string, error) {
+			if d.Location != nil {
+				v = v.In(d.Location)
+			}
+			if format == "" {
+				format = d.DateLayout
+			}
+			return base(v, format)
+		})
+	}
+
+	if d.DecimalSeparator != "" && d.DecimalSeparator != "." {
+		base := e.formatters[reflect.TypeFor[float64]()]
+		RegisterFormatter(clone, func(v float64, format string) (string, error) {
+			s, err := base(v, format)
+			if err != nil {
+				return "", err
+			}
+			return strings.Replace(s, ".", d.DecimalSeparator, 1), nil
+		})
+	}
+
+	return clone
+}
@@ -6,41 +6,20 @@ import (
 	"fmt"
 	"io"
 	"reflect"
-	"strconv"
-	"strings"
-	"time"
 	"unicode"
-
-	"github.com/google/uuid"
 )
 
-func GetStructColumnNames(data any) ([]string, error) {
-	v := reflect.ValueOf(data)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
-	}
+var defaultExporter = NewExporter()
 
-	if v.Kind() != reflect.Struct {
-		return nil, errors.New("data must be a struct or pointer to struct")
-	}
-
-	t := v.Type()
-	cols := make([]string, 0, t.NumField())
-
-	for i := range t.NumField() {
-		field := t.Field(i)
-		jsonTag := field.Tag.Get("json")
-		if jsonTag != "" && jsonTag != "-" {
-			if idx := strings.Index(jsonTag, ","); idx != -1 {
-				jsonTag = jsonTag[:idx]
-			}
-			cols = append(cols, formatColumnName(jsonTag))
-		} else {
-			cols = append(cols, formatColumnName(field.Name))
-		}
+// GetStructColumnNames returns the column names of data using the default
+// exporter.
+func GetStructColumnNames(data any) ([]string, error) {
+	schema, err := defaultExporter.Schema(data, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	return cols, nil
+	return schema.Header(), nil
 }
 
 func formatColumnName(name string) string {
@@ -55,41 +34,14 @@ func formatColumnName(name string) string {
 	return string(runes)
 }
 
+// ConvertStructToCSV formats data as a CSV record using the default exporter.
 func ConvertStructToCSV(data any) ([]string, error) {
-	v := reflect.ValueOf(data)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
-	}
-
-	if v.Kind() != reflect.Struct {
-		return nil, errors.New("data must be a struct or pointer to struct")
-	}
-
-	t := v.Type()
-	record := make([]string, 0, t.NumField())
-
-	for i := range v.NumField() {
-		field := v.Field(i)
-		fieldValue := field.Interface()
-
-		var strValue string
-		switch val := fieldValue.(type) {
-		case uuid.UUID:
-			strValue = val.String()
-		case time.Time:
-			strValue = val.Format(time.RFC3339)
-		case float64:
-			strValue = strconv.FormatFloat(val, 'f', 2, 64)
-		case string:
-			strValue = val
-		default:
-			strValue = fmt.Sprintf("%v", val)
-		}
-
-		record = append(record, strValue)
+	schema, err := defaultExporter.Schema(data, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	return record, nil
+	return schema.Record(data)
 }
 
 func getSampleData(v reflect.Value) any {
@@ -107,6 +59,10 @@ func getSampleData(v reflect.Value) any {
 	return reflect.New(elemType).Interface()
 }
 
+// WriteItemsCSV writes a slice of structs as CSV using reflection.
+//
+// Deprecated: use WriteCSVSeq with typed columns, which does not need the
+// whole slice in memory.
 func WriteItemsCSV(w io.Writer, cols []string, items any) error {
 	writer := csv.NewWriter(w)
 	defer writer.Flush()
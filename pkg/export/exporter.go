@@ -0,0 +1,387 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	tagOmitEmpty    = "omitempty"
+	tagFormatPrefix = "format="
+)
+
+// FormatFunc converts a field value to text. format is the value of the
+// format= option of the field's csv tag and is empty when there is none.
+type FormatFunc func(value any, format string) (string, error)
+
+// Exporter turns structs into CSV records. Columns are described by csv
+// struct tags of the form `csv:"name,omitempty,format=..."`; fields without
+// a csv tag fall back to their json name and `csv:"-"` hides a field.
+// Values are rendered by formatters registered per Go type.
+type Exporter struct {
+	formatters map[reflect.Type]FormatFunc
+}
+
+// Schema is the resolved list of columns for one struct type.
+type Schema struct {
+	exporter *Exporter
+	typ      reflect.Type
+	fields   []field
+}
+
+type field struct {
+	index     []int
+	name      string
+	omitEmpty bool
+	format    string
+}
+
+// NewExporter returns an exporter with formatters for strings, UUIDs, times
+// (format is a time layout, RFC3339 by default) and floats (format is a
+// fmt verb, two decimals by default).
+func NewExporter() *Exporter {
+	e := &Exporter{formatters: make(map[reflect.Type]FormatFunc)}
+
+	RegisterFormatter(e, func(v string, _ string) (string, error) {
+		return v, nil
+	})
+	RegisterFormatter(e, func(v uuid.UUID, _ string) (string, error) {
+		return v.String(), nil
+	})
+	RegisterFormatter(e, func(v time.Time, format string) (string, error) {
+		if format == "" {
+			format = time.RFC3339
+		}
+		return v.Format(format), nil
+	})
+	RegisterFormatter(e, func(v float64, format string) (string, error) {
+		if format == "" {
+			return strconv.FormatFloat(v, 'f', 2, 64), nil
+		}
+		return fmt.Sprintf(format, v), nil
+	})
+
+	return e
+}
+
+// Register sets the formatter used for values of type t, replacing any
+// previous one.
+func (e *Exporter) Register(t reflect.Type, fn FormatFunc) {
+	e.formatters[t] = fn
+}
+
+// RegisterFormatter is a typed shorthand for Exporter.Register.
+func RegisterFormatter[T any](e *Exporter, fn func(v T, format string) (string, error)) {
+	e.Register(reflect.TypeFor[T](), func(value any, format string) (string, error) {
+		v, ok := value.(T)
+		if !ok {
+			return "", fmt.Errorf("formatter for %s got %T", reflect.TypeFor[T](), value)
+		}
+		return fn(v, format)
+	})
+}
+
+// Schema resolves the columns of sample, a struct or pointer to struct. When
+// columns is not empty only those columns are kept, in the given order.
+func (e *Exporter) Schema(sample any, columns []string) (*Schema, error) {
+	t := reflect.TypeOf(sample)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.New("data must be a struct or pointer to struct")
+	}
+
+	fields := structFields(t, nil)
+	if len(columns) > 0 {
+		byName := make(map[string]field, len(fields))
+		for _, f := range fields {
+			byName[f.name] = f
+		}
+
+		selected := make([]field, 0, len(columns))
+		for _, name := range columns {
+			f, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("%w '%s'", ErrUnknownColumn, name)
+			}
+			selected = append(selected, f)
+		}
+		fields = selected
+	}
+
+	return &Schema{exporter: e, typ: t, fields: fields}, nil
+}
+
+// WriteCSV writes a header and one record per element of items, which must
+// be a slice of structs or of pointers to structs.
+func (e *Exporter) WriteCSV(w io.Writer, items any, columns []string) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	v := reflect.ValueOf(items)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Slice {
+		return errors.New("items must be a slice")
+	}
+
+	schema, err := e.Schema(getSampleData(v), columns)
+	if err != nil {
+		return fmt.Errorf("Schema: %w", err)
+	}
+
+	if err = writer.Write(schema.Header()); err != nil {
+		return fmt.Errorf("writer.Write: %w", err)
+	}
+
+	for i := range v.Len() {
+		record, recordErr := schema.Record(v.Index(i).Interface())
+		if recordErr != nil {
+			return fmt.Errorf("Record: %w", recordErr)
+		}
+
+		if writeErr := writer.Write(record); writeErr != nil {
+			return fmt.Errorf("writer.Write: %w", writeErr)
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) Header() []string {
+	header := make([]string, len(s.fields))
+	for i, f := range s.fields {
+		header[i] = f.name
+	}
+
+	return header
+}
+
+// Record formats data, which must be of the schema's struct type or a
+// pointer to it, as a CSV record.
+func (s *Schema) Record(data any) ([]string, error) {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Type() != s.typ {
+		return nil, fmt.Errorf("schema is for %s, got %s", s.typ, v.Type())
+	}
+
+	record := make([]string, len(s.fields))
+	for i, f := range s.fields {
+		str, err := s.exporter.formatValue(v.FieldByIndex(f.index), f)
+		if err != nil {
+			return nil, fmt.Errorf("column '%s': %w", f.name, err)
+		}
+		record[i] = str
+	}
+
+	return record, nil
+}
+
+// Cells converts data like Record but keeps numbers and times typed for an
+// XLSX sheet. Integers become IntCell, times DateCell and floats MoneyCell,
+// or NumberCell when the tag sets a format; other values are formatted to
+// text.
+func (s *Schema) Cells(data any) ([]Cell, error) {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Type() != s.typ {
+		return nil, fmt.Errorf("schema is for %s, got %s", s.typ, v.Type())
+	}
+
+	cells := make([]Cell, len(s.fields))
+	for i, f := range s.fields {
+		cell, err := s.exporter.cellValue(v.FieldByIndex(f.index), f)
+		if err != nil {
+			return nil, fmt.Errorf("column '%s': %w", f.name, err)
+		}
+		cells[i] = cell
+	}
+
+	return cells, nil
+}
+
+// StructColumns returns the columns of the struct type T, or of the struct T
+// points to, as typed columns for WriteCSVSeq and WriteSheetSeq, so csv tags
+// and formatters also drive streaming exports. Values are rendered like
+// Record and Cells; a value its formatter rejects is left empty.
+func StructColumns[T any](e *Exporter) ([]Column[T], error) {
+	var zero T
+
+	schema, err := e.Schema(zero, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	row := func(data T) reflect.Value {
+		v := reflect.ValueOf(data)
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		return v
+	}
+
+	columns := make([]Column[T], len(schema.fields))
+	for i, f := range schema.fields {
+		columns[i] = Column[T]{
+			Header: f.name,
+			Value: func(data T) string {
+				str, _ := e.formatValue(row(data).FieldByIndex(f.index), f)
+				return str
+			},
+			Cell: func(data T) Cell {
+				cell, _ := e.cellValue(row(data).FieldByIndex(f.index), f)
+				return cell
+			},
+		}
+	}
+
+	return columns, nil
+}
+
+func (e *Exporter) cellValue(v reflect.Value, f field) (Cell, error) {
+	if f.omitEmpty && v.IsZero() {
+		return EmptyCell(), nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return EmptyCell(), nil
+		}
+		v = v.Elem()
+	}
+
+	switch val := v.Interface().(type) {
+	case time.Time:
+		return DateCell(val), nil
+	case float32, float64:
+		if f.format != "" {
+			return NumberCell(v.Float()), nil
+		}
+		return MoneyCell(v.Float()), nil
+	}
+
+	if v.CanInt() {
+		return IntCell(int(v.Int())), nil
+	}
+
+	str, err := e.formatValue(v, f)
+	if err != nil {
+		return Cell{}, err
+	}
+
+	return StringCell(str), nil
+}
+
+func (e *Exporter) formatValue(v reflect.Value, f field) (string, error) {
+	if f.omitEmpty && v.IsZero() {
+		return "", nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if fn, ok := e.formatters[v.Type()]; ok {
+		return fn(v.Interface(), f.format)
+	}
+
+	if f.format != "" {
+		return fmt.Sprintf(f.format, v.Interface()), nil
+	}
+
+	return fmt.Sprintf("%v", v.Interface()), nil
+}
+
+// structFields lists the exported fields of t in declaration order. Untagged
+// embedded structs are flattened into the parent.
+func structFields(t reflect.Type, parent []int) []field {
+	var fields []field
+
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+
+		tag, hasTag := sf.Tag.Lookup("csv")
+		if tag == "-" {
+			continue
+		}
+
+		if sf.Anonymous && !hasTag && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, structFields(sf.Type, index)...)
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		f := field{index: index}
+		if hasTag {
+			f.name, f.omitEmpty, f.format = parseCSVTag(tag)
+		}
+		if f.name == "" {
+			f.name = fallbackColumnName(sf)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// parseCSVTag splits `name,omitempty,format=...`. Everything after format=
+// is the format itself, so layouts may contain commas.
+func parseCSVTag(tag string) (string, bool, string) {
+	name, opts, _ := strings.Cut(tag, ",")
+
+	var omitEmpty bool
+	for opts != "" {
+		if format, ok := strings.CutPrefix(opts, tagFormatPrefix); ok {
+			return name, omitEmpty, format
+		}
+
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == tagOmitEmpty {
+			omitEmpty = true
+		}
+	}
+
+	return name, omitEmpty, ""
+}
+
+func fallbackColumnName(sf reflect.StructField) string {
+	jsonTag := sf.Tag.Get("json")
+	if jsonTag != "" && jsonTag != "-" {
+		if idx := strings.Index(jsonTag, ","); idx != -1 {
+			jsonTag = jsonTag[:idx]
+		}
+		if jsonTag != "" {
+			return formatColumnName(jsonTag)
+		}
+	}
+
+	return formatColumnName(sf.Name)
+}
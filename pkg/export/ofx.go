@@ -0,0 +1,165 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OFXContentType is the media type of OFX files.
+const OFXContentType = "application/x-ofx"
+
+const (
+	ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n" +
+		`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	ofxTimeLayout = "20060102150405"
+	// ofxNameLength is the maximum length of the NAME element.
+	ofxNameLength = 32
+)
+
+// OFXStatement describes the bank account and period of an OFX statement.
+type OFXStatement struct {
+	BankID    string
+	AccountID string
+	Currency  string
+	Created   time.Time
+	From      time.Time
+	To        time.Time
+}
+
+// OFXTransaction is a STMTTRN record. A positive Amount is a credit, a
+// negative one a debit. ID becomes the FITID, which importers use to skip
+// transactions they have already seen, so it must not change between
+// exports.
+type OFXTransaction struct {
+	ID     string
+	Date   time.Time
+	Amount float64
+	Name   string
+	Memo   string
+}
+
+// OFXWriter writes an OFX 2.2 bank statement. The statement has no
+// LEDGERBAL: the items are not a full account history, and importers would
+// take the sum of the written transactions for the account balance.
+type OFXWriter struct {
+	bw *bufio.Writer
+}
+
+// NewOFXWriter writes the statement header and returns a writer for the
+// transactions. Close must be called to finish the file.
+func NewOFXWriter(w io.Writer, st OFXStatement) (*OFXWriter, error) {
+	o := &OFXWriter{bw: bufio.NewWriter(w)}
+
+	currency := st.Currency
+	if currency == "" {
+		currency = "RUB"
+	}
+
+	err := o.write(ofxHeader,
+		"<OFX>\n",
+		"<SIGNONMSGSRSV1><SONRS>\n",
+		"<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n",
+		ofxElement("DTSERVER", ofxTime(st.Created)),
+		ofxElement("LANGUAGE", "RUS"),
+		"</SONRS></SIGNONMSGSRSV1>\n",
+		"<BANKMSGSRSV1><STMTTRNRS>\n",
+		ofxElement("TRNUID", "0"),
+		"<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n",
+		"<STMTRS>\n",
+		ofxElement("CURDEF", currency),
+		"<BANKACCTFROM>\n",
+		ofxElement("BANKID", st.BankID),
+		ofxElement("ACCTID", st.AccountID),
+		ofxElement("ACCTTYPE", "CHECKING"),
+		"</BANKACCTFROM>\n",
+		"<BANKTRANLIST>\n",
+		ofxElement("DTSTART", ofxTime(st.From)),
+		ofxElement("DTEND", ofxTime(st.To)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// WriteTransaction writes one STMTTRN record.
+func (o *OFXWriter) WriteTransaction(t OFXTransaction) error {
+	trnType := "CREDIT"
+	if t.Amount < 0 {
+		trnType = "DEBIT"
+	}
+
+	parts := []string{
+		"<STMTTRN>\n",
+		ofxElement("TRNTYPE", trnType),
+		ofxElement("DTPOSTED", ofxTime(t.Date)),
+		ofxElement("TRNAMT", strconv.FormatFloat(t.Amount, 'f', 2, 64)),
+		ofxElement("FITID", t.ID),
+		ofxElement("NAME", truncateRunes(t.Name, ofxNameLength)),
+	}
+	if t.Memo != "" {
+		parts = append(parts, ofxElement("MEMO", t.Memo))
+	}
+	parts = append(parts, "</STMTTRN>\n")
+
+	return o.write(parts...)
+}
+
+// Close closes the open elements and flushes the output.
+func (o *OFXWriter) Close() error {
+	err := o.write(
+		"</BANKTRANLIST>\n",
+		"</STMTRS>\n",
+		"</STMTTRNRS></BANKMSGSRSV1>\n",
+		"</OFX>\n",
+	)
+	if err != nil {
+		return err
+	}
+
+	if err = o.bw.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
+
+func (o *OFXWriter) write(parts ...string) error {
+	for _, part := range parts {
+		if _, err := o.bw.WriteString(part); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func ofxElement(name, value string) string {
+	var b strings.Builder
+	b.WriteString("<" + name + ">")
+	_ = xml.EscapeText(&b, []byte(value))
+	b.WriteString("</" + name + ">\n")
+
+	return b.String()
+}
+
+// ofxTime formats t in UTC with an explicit zone, as OFX dates without one
+// are read in the importer's local time.
+func ofxTime(t time.Time) string {
+	return t.UTC().Format(ofxTimeLayout) + ".000[0:GMT]"
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n])
+}
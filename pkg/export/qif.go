@@ -0,0 +1,89 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QIFContentType is the media type of QIF files.
+const QIFContentType = "application/qif"
+
+// qifDateLayout is the US month/day/year order that QIF importers expect by
+// default.
+const qifDateLayout = "01/02/2006"
+
+// QIFTransaction is a !Type:Bank entry. A positive Amount is a deposit, a
+// negative one a payment.
+type QIFTransaction struct {
+	Date     time.Time
+	Amount   float64
+	Payee    string
+	Category string
+	Memo     string
+}
+
+// QIFWriter writes bank transactions in the Quicken Interchange Format.
+type QIFWriter struct {
+	bw *bufio.Writer
+}
+
+// NewQIFWriter writes the !Type:Bank header. Close must be called to flush
+// the output.
+func NewQIFWriter(w io.Writer) (*QIFWriter, error) {
+	q := &QIFWriter{bw: bufio.NewWriter(w)}
+
+	if err := q.writeLines("!Type:Bank"); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// WriteTransaction writes one entry terminated by ^. Empty optional fields
+// are left out.
+func (q *QIFWriter) WriteTransaction(t QIFTransaction) error {
+	lines := []string{
+		"D" + t.Date.Format(qifDateLayout),
+		"T" + strconv.FormatFloat(t.Amount, 'f', 2, 64),
+	}
+
+	for _, field := range []struct{ code, value string }{
+		{"P", t.Payee},
+		{"L", t.Category},
+		{"M", t.Memo},
+	} {
+		if field.value != "" {
+			lines = append(lines, field.code+qifValue(field.value))
+		}
+	}
+
+	return q.writeLines(append(lines, "^")...)
+}
+
+// Close flushes the output.
+func (q *QIFWriter) Close() error {
+	if err := q.bw.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
+
+func (q *QIFWriter) writeLines(lines ...string) error {
+	for _, line := range lines {
+		if _, err := q.bw.WriteString(line + "\n"); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// qifValue keeps a field on one line.
+func qifValue(value string) string {
+	return strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(value)
+}
@@ -0,0 +1,113 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+var ErrUnknownColumn = errors.New("unknown column")
+
+// Column is a typed export column: a header and an extractor. Cell renders
+// the value for XLSX sheets; when it is nil the Value text is written as a
+// string cell.
+type Column[T any] struct {
+	Header string
+	Value  func(T) string
+	Cell   func(T) Cell
+}
+
+// SelectColumns returns the columns whose headers are listed in names, in
+// that order. An empty names keeps all columns.
+func SelectColumns[T any](columns []Column[T], names []string) ([]Column[T], error) {
+	if len(names) == 0 {
+		return columns, nil
+	}
+
+	byHeader := make(map[string]Column[T], len(columns))
+	for _, col := range columns {
+		byHeader[col.Header] = col
+	}
+
+	selected := make([]Column[T], 0, len(names))
+	for _, name := range names {
+		col, ok := byHeader[name]
+		if !ok {
+			return nil, fmt.Errorf("%w '%s'", ErrUnknownColumn, name)
+		}
+		selected = append(selected, col)
+	}
+
+	return selected, nil
+}
+
+// WriteCSVSeq writes a header and one record per value of rows and flushes
+// the writer. It returns the number of records written, not counting the
+// header. Iteration stops at the first write error.
+func WriteCSVSeq[T any](w *csv.Writer, columns []Column[T], rows iter.Seq[T]) (int, error) {
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Header
+	}
+
+	if err := w.Write(header); err != nil {
+		return 0, fmt.Errorf("writer.Write: %w", err)
+	}
+
+	var count int
+	var err error
+	record := make([]string, len(columns))
+
+	for row := range rows {
+		for i, col := range columns {
+			record[i] = col.Value(row)
+		}
+
+		if err = w.Write(record); err != nil {
+			return count, fmt.Errorf("writer.Write: %w", err)
+		}
+		count++
+	}
+
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return count, fmt.Errorf("writer.Flush: %w", err)
+	}
+
+	return count, nil
+}
+
+// WriteSheetSeq adds a sheet with a frozen header and writes one row per
+// value of rows. It returns the number of rows written, not counting the
+// header.
+func WriteSheetSeq[T any](x *XLSXWriter, name string, columns []Column[T], rows iter.Seq[T]) (int, error) {
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Header
+	}
+
+	if err := x.AddSheet(name, header); err != nil {
+		return 0, err
+	}
+
+	var count int
+	cells := make([]Cell, len(columns))
+
+	for row := range rows {
+		for i, col := range columns {
+			if col.Cell != nil {
+				cells[i] = col.Cell(row)
+			} else {
+				cells[i] = StringCell(col.Value(row))
+			}
+		}
+
+		if err := x.WriteRow(cells...); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
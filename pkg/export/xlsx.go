@@ -0,0 +1,353 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	XLSXContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	maxSheetNameLength = 31
+	lettersInAlphabet  = 26
+)
+
+// Style indexes into cellXfs of xlsxStyles.
+const (
+	styleDefault = iota
+	styleMoney
+	styleDate
+	styleHeader
+	styleNumber
+)
+
+type cellKind int
+
+const (
+	cellEmpty cellKind = iota
+	cellString
+	cellNumber
+	cellDate
+)
+
+// excelEpoch is day zero of the 1900 date system. Serial numbers are counted
+// from it, which already accounts for the 1900 leap year bug for dates after
+// February 1900.
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// Cell is a single typed worksheet value.
+type Cell struct {
+	kind  cellKind
+	str   string
+	num   float64
+	style int
+}
+
+func EmptyCell() Cell {
+	return Cell{}
+}
+
+func StringCell(s string) Cell {
+	return Cell{kind: cellString, str: s}
+}
+
+func IntCell(n int) Cell {
+	return Cell{kind: cellNumber, num: float64(n)}
+}
+
+// MoneyCell is a numeric cell shown with two decimals and thousands grouping.
+func MoneyCell(v float64) Cell {
+	return Cell{kind: cellNumber, num: v, style: styleMoney}
+}
+
+// NumberCell is a numeric cell shown with two decimals and no grouping, for
+// values such as percentages.
+func NumberCell(v float64) Cell {
+	return Cell{kind: cellNumber, num: v, style: styleNumber}
+}
+
+// DateCell stores the wall-clock time of t as an Excel date, so convert t to
+// the desired location first.
+func DateCell(t time.Time) Cell {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	serial := wall.Sub(excelEpoch).Hours() / float64(24)
+
+	return Cell{kind: cellDate, num: serial, style: styleDate}
+}
+
+// XLSXWriter writes an Office Open XML workbook. Sheets are written one after
+// another and rows go straight into the zip archive, so a sheet of any size
+// is never held in memory.
+type XLSXWriter struct {
+	zw     *zip.Writer
+	sheets []string
+	sheet  io.Writer
+	row    int
+	closed bool
+}
+
+func NewXLSXWriter(w io.Writer) *XLSXWriter {
+	return &XLSXWriter{zw: zip.NewWriter(w)}
+}
+
+// AddSheet finishes the current sheet and starts a new one. A non-empty
+// header is written as a bold first row that stays frozen while scrolling.
+func (x *XLSXWriter) AddSheet(name string, header []string) error {
+	if x.closed {
+		return errors.New("xlsx writer is closed")
+	}
+
+	if err := validateSheetName(name, x.sheets); err != nil {
+		return err
+	}
+
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+
+	x.sheets = append(x.sheets, name)
+	sheet, err := x.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(x.sheets)))
+	if err != nil {
+		return fmt.Errorf("zip.Create: %w", err)
+	}
+	x.sheet, x.row = sheet, 0
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(header) > 0 {
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+		b.WriteString(`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+		b.WriteString(`</sheetView></sheetViews>`)
+	}
+	b.WriteString(`<sheetFormatPr defaultRowHeight="15" defaultColWidth="20"/>`)
+	b.WriteString(`<sheetData>`)
+
+	if _, err = io.WriteString(x.sheet, b.String()); err != nil {
+		return fmt.Errorf("write sheet: %w", err)
+	}
+
+	if len(header) == 0 {
+		return nil
+	}
+
+	cells := make([]Cell, len(header))
+	for i, name := range header {
+		cells[i] = Cell{kind: cellString, str: name, style: styleHeader}
+	}
+
+	return x.WriteRow(cells...)
+}
+
+// WriteRow appends a row to the current sheet.
+func (x *XLSXWriter) WriteRow(cells ...Cell) error {
+	if x.sheet == nil {
+		return errors.New("no sheet to write to, call AddSheet first")
+	}
+
+	x.row++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, x.row)
+	for i, cell := range cells {
+		writeCell(&b, columnName(i)+strconv.Itoa(x.row), cell)
+	}
+	b.WriteString(`</row>`)
+
+	if _, err := io.WriteString(x.sheet, b.String()); err != nil {
+		return fmt.Errorf("write row: %w", err)
+	}
+
+	return nil
+}
+
+// Close finishes the last sheet and writes the workbook parts. It does not
+// close the underlying writer.
+func (x *XLSXWriter) Close() error {
+	if x.closed {
+		return nil
+	}
+
+	if len(x.sheets) == 0 {
+		return errors.New("workbook must have at least one sheet")
+	}
+
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+	x.closed = true
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", x.contentTypes()},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", x.workbook()},
+		{"xl/_rels/workbook.xml.rels", x.workbookRels()},
+		{"xl/styles.xml", xlsxStyles},
+	}
+
+	for _, part := range parts {
+		f, err := x.zw.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("zip.Create: %w", err)
+		}
+		if _, err = io.WriteString(f, part.content); err != nil {
+			return fmt.Errorf("write %s: %w", part.name, err)
+		}
+	}
+
+	if err := x.zw.Close(); err != nil {
+		return fmt.Errorf("zip.Close: %w", err)
+	}
+
+	return nil
+}
+
+func (x *XLSXWriter) endSheet() error {
+	if x.sheet == nil {
+		return nil
+	}
+
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return fmt.Errorf("write sheet: %w", err)
+	}
+	x.sheet = nil
+
+	return nil
+}
+
+func (x *XLSXWriter) contentTypes() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ` +
+		`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ` +
+		`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range x.sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" `+
+			`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+
+	return b.String()
+}
+
+func (x *XLSXWriter) workbook() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, name := range x.sheets {
+		b.WriteString(`<sheet name="`)
+		writeEscaped(&b, name)
+		fmt.Fprintf(&b, `" sheetId="%d" r:id="rId%d"/>`, i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+
+	return b.String()
+}
+
+func (x *XLSXWriter) workbookRels() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range x.sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" `+
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" `+
+			`Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" `+
+		`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" `+
+		`Target="styles.xml"/>`, len(x.sheets)+1)
+	b.WriteString(`</Relationships>`)
+
+	return b.String()
+}
+
+func writeCell(b *strings.Builder, ref string, cell Cell) {
+	switch cell.kind {
+	case cellString:
+		fmt.Fprintf(b, `<c r="%s" t="inlineStr" s="%d"><is><t xml:space="preserve">`, ref, cell.style)
+		writeEscaped(b, cell.str)
+		b.WriteString(`</t></is></c>`)
+	case cellNumber, cellDate:
+		if math.IsNaN(cell.num) || math.IsInf(cell.num, 0) {
+			return
+		}
+		fmt.Fprintf(b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.style, strconv.FormatFloat(cell.num, 'f', -1, 64))
+	case cellEmpty:
+	}
+}
+
+func writeEscaped(b *strings.Builder, s string) {
+	// strings.Builder never returns a write error.
+	_ = xml.EscapeText(b, []byte(s))
+}
+
+// columnName converts a zero-based column index to its letter name: A, B, ..., Z, AA.
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / lettersInAlphabet {
+		name = string(rune('A'+(i-1)%lettersInAlphabet)) + name
+	}
+
+	return name
+}
+
+func validateSheetName(name string, existing []string) error {
+	if name == "" || len([]rune(name)) > maxSheetNameLength {
+		return fmt.Errorf("sheet name '%s' must be 1 to %d characters long", name, maxSheetNameLength)
+	}
+
+	if strings.ContainsAny(name, `[]:*?/\`) {
+		return fmt.Errorf("sheet name '%s' contains a forbidden character", name)
+	}
+
+	for _, other := range existing {
+		if strings.EqualFold(other, name) {
+			return fmt.Errorf("duplicate sheet name '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+const xlsxRootRels = xml.Header +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" ` +
+	`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" ` +
+	`Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles defines the cell formats referenced by the style* constants:
+// general, money (#,##0.00), date-time, bold header and number (0.00).
+const xlsxStyles = xml.Header +
+	`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill>` +
+	`<fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="5">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,25 @@
+// Package groupby parses the custom group_by intervals of analytics, such as
+// "3d" or "2w", shared by request validation and query building.
+package groupby
+
+import "strconv"
+
+// SplitInterval splits a custom interval into its count and unit, one of
+// 'h', 'd' or 'w'. It reports false for anything else.
+func SplitInterval(groupBy string) (int, byte, bool) {
+	if len(groupBy) < 2 {
+		return 0, 0, false
+	}
+
+	n, err := strconv.Atoi(groupBy[:len(groupBy)-1])
+	if err != nil || n <= 0 {
+		return 0, 0, false
+	}
+
+	unit := groupBy[len(groupBy)-1]
+	if unit != 'h' && unit != 'd' && unit != 'w' {
+		return 0, 0, false
+	}
+
+	return n, unit, true
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gookit/slog"
+	"github.com/kstsm/wb-sales-tracker/pkg/groupby"
 )
 
 type Validate struct {
@@ -35,6 +36,20 @@ func NewValidator() *Validate {
 		os.Exit(1)
 	}
 
+	if err := validate.RegisterValidation("group_by", ValidateGroupBy); err != nil {
+		slog.Fatal("Failed to register group_by validation", "error", err)
+		os.Exit(1)
+	}
+
+	if err := validate.RegisterValidation("pivot_dimension", ValidatePivotDimension); err != nil {
+		slog.Fatal("Failed to register pivot_dimension validation", "error", err)
+		os.Exit(1)
+	}
+	if err := validate.RegisterValidation("pivot_measure", ValidatePivotMeasure); err != nil {
+		slog.Fatal("Failed to register pivot_measure validation", "error", err)
+		os.Exit(1)
+	}
+
 	return &Validate{Validate: validate}
 }
 
@@ -86,3 +101,38 @@ func ValidateSortOrder(fl validator.FieldLevel) bool {
 	value := fl.Field().String()
 	return value == "asc" || value == "desc"
 }
+
+func ValidateGroupBy(fl validator.FieldLevel) bool {
+	field := fl.Field()
+
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return true
+		}
+		field = field.Elem()
+	}
+
+	value := field.String()
+	switch value {
+	case "day", "week", "month", "quarter", "year", "weekday", "hour", "category":
+		return true
+	}
+
+	_, _, ok := groupby.SplitInterval(value)
+
+	return ok
+}
+
+func ValidatePivotDimension(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	return value == "type" || ValidateGroupBy(fl)
+}
+
+func ValidatePivotMeasure(fl validator.FieldLevel) bool {
+	switch fl.Field().String() {
+	case "sum", "count", "avg", "median", "p90", "income", "expense", "net":
+		return true
+	default:
+		return false
+	}
+}
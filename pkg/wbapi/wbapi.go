@@ -0,0 +1,174 @@
+// Package wbapi is a client for the Wildberries statistics API. Its methods
+// return the records changed since a date, one page per call; the next page
+// starts at the LastChangeDate of the last record.
+package wbapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the production address of the statistics API.
+const DefaultBaseURL = "https://statistics-api.wildberries.ru"
+
+const maxErrorBody = 512
+
+var (
+	ErrUnauthorized = errors.New("wb api: invalid or expired token")
+	ErrRateLimited  = errors.New("wb api: too many requests")
+)
+
+// Moscow is the zone of the dates the API returns without an offset.
+var Moscow = time.FixedZone("MSK", 3*60*60)
+
+// StatusError is returned for unexpected HTTP statuses.
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("wb api: unexpected status %d: %s", e.Code, e.Body)
+}
+
+// Time is a date in the API format, 2006-01-02T15:04:05 in Moscow time.
+type Time struct {
+	time.Time
+}
+
+func (t *Time) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid date %s", data)
+	}
+	if s == "" || strings.HasPrefix(s, "0001-01-01") {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	if parsed, parseErr := time.Parse(time.RFC3339, s); parseErr == nil {
+		t.Time = parsed
+		return nil
+	}
+
+	parsed, err := time.ParseInLocation("2006-01-02T15:04:05", s, Moscow)
+	if err != nil {
+		return fmt.Errorf("invalid date '%s'", s)
+	}
+	t.Time = parsed
+
+	return nil
+}
+
+// Sale is a record of the sales method: a sale when SaleID starts with S, a
+// return when it starts with R. ForPay, the amount paid out to the seller,
+// is in rubles and negative for returns.
+type Sale struct {
+	Date           Time    `json:"date"`
+	LastChangeDate Time    `json:"lastChangeDate"`
+	SRID           string  `json:"srid"`
+	SaleID         string  `json:"saleID"`
+	Subject        string  `json:"subject"`
+	ForPay         float64 `json:"forPay"`
+}
+
+// IsReturn reports whether the record is a customer return.
+func (s Sale) IsReturn() bool {
+	return strings.HasPrefix(s.SaleID, "R")
+}
+
+// Order is a record of the orders method. PriceWithDisc, the price after
+// the seller's discount, is in rubles.
+type Order struct {
+	Date           Time    `json:"date"`
+	LastChangeDate Time    `json:"lastChangeDate"`
+	SRID           string  `json:"srid"`
+	Subject        string  `json:"subject"`
+	PriceWithDisc  float64 `json:"priceWithDisc"`
+	IsCancel       bool    `json:"isCancel"`
+}
+
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient returns a client for the API at baseURL, or at DefaultBaseURL
+// when it is empty.
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Sales returns the sales and returns changed since dateFrom.
+func (c *Client) Sales(ctx context.Context, dateFrom time.Time) ([]Sale, error) {
+	var sales []Sale
+	if err := c.get(ctx, "/api/v1/supplier/sales", dateFrom, &sales); err != nil {
+		return nil, err
+	}
+
+	return sales, nil
+}
+
+// Orders returns the orders changed since dateFrom.
+func (c *Client) Orders(ctx context.Context, dateFrom time.Time) ([]Order, error) {
+	var orders []Order
+	if err := c.get(ctx, "/api/v1/supplier/orders", dateFrom, &orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, dateFrom time.Time, dst any) error {
+	query := url.Values{}
+	query.Set("dateFrom", dateFrom.In(Moscow).Format("2006-01-02T15:04:05"))
+	query.Set("flag", "0")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("wb api: %w", err)
+	}
+	req.Header.Set("Authorization", c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("wb api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &StatusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+
+	// An empty body means there are no changes.
+	if err = json.NewDecoder(resp.Body).Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("wb api: decode %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package wbapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testToken = "test-token"
+
+// newTestServer serves body for path and checks the request the client
+// makes.
+func newTestServer(t *testing.T, path, body string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("path = %s, want %s", r.URL.Path, path)
+		}
+		if got := r.Header.Get("Authorization"); got != testToken {
+			t.Errorf("Authorization = %q, want %q", got, testToken)
+		}
+		if got := r.URL.Query().Get("dateFrom"); got != "2025-01-01T03:00:00" {
+			t.Errorf("dateFrom = %s, want the cursor in Moscow time", got)
+		}
+		if got := r.URL.Query().Get("flag"); got != "0" {
+			t.Errorf("flag = %s, want 0", got)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestClientSales(t *testing.T) {
+	srv := newTestServer(t, "/api/v1/supplier/sales", `[
+		{"date": "2025-01-02T10:00:00", "lastChangeDate": "2025-01-02T10:05:00", "srid": "a1",
+		 "saleID": "S1", "subject": "Платья", "forPay": 950.5},
+		{"date": "2025-01-03T11:00:00", "lastChangeDate": "2025-01-03T11:05:00", "srid": "a2",
+		 "saleID": "R2", "subject": "Платья", "forPay": -950.5}
+	]`)
+
+	client := NewClient(srv.URL, testToken, time.Second)
+	sales, err := client.Sales(context.Background(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Sales: %v", err)
+	}
+
+	if len(sales) != 2 {
+		t.Fatalf("got %d sales, want 2", len(sales))
+	}
+	if want := time.Date(2025, 1, 2, 7, 5, 0, 0, time.UTC); !sales[0].LastChangeDate.Equal(want) {
+		t.Errorf("LastChangeDate = %s, want %s", sales[0].LastChangeDate.Time, want)
+	}
+	if sales[0].IsReturn() || !sales[1].IsReturn() {
+		t.Errorf("IsReturn = %v, %v, want false, true", sales[0].IsReturn(), sales[1].IsReturn())
+	}
+	if sales[0].ForPay != 950.5 {
+		t.Errorf("ForPay = %v, want 950.5", sales[0].ForPay)
+	}
+}
+
+func TestClientOrders(t *testing.T) {
+	srv := newTestServer(t, "/api/v1/supplier/orders", `[
+		{"date": "2025-01-02T10:00:00", "lastChangeDate": "2025-01-02T10:05:00", "srid": "o1",
+		 "subject": "Платья", "priceWithDisc": 1200, "isCancel": true}
+	]`)
+
+	client := NewClient(srv.URL, testToken, time.Second)
+	orders, err := client.Orders(context.Background(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Orders: %v", err)
+	}
+
+	if len(orders) != 1 || orders[0].SRID != "o1" || !orders[0].IsCancel || orders[0].PriceWithDisc != 1200 {
+		t.Fatalf("got %+v", orders)
+	}
+}
+
+func TestClientEmptyBody(t *testing.T) {
+	srv := newTestServer(t, "/api/v1/supplier/sales", "")
+
+	client := NewClient(srv.URL, testToken, time.Second)
+	sales, err := client.Sales(context.Background(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Sales: %v", err)
+	}
+	if len(sales) != 0 {
+		t.Fatalf("got %d sales, want none", len(sales))
+	}
+}
+
+func TestClientStatusErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   error
+	}{
+		{"unauthorized", http.StatusUnauthorized, ErrUnauthorized},
+		{"rate limited", http.StatusTooManyRequests, ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			_, err := NewClient(srv.URL, testToken, time.Second).Sales(context.Background(), time.Now())
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("unexpected", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "upstream failed", http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		_, err := NewClient(srv.URL, testToken, time.Second).Orders(context.Background(), time.Now())
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.Code != http.StatusBadGateway || statusErr.Body != "upstream failed" {
+			t.Fatalf("err = %v, want a StatusError with code 502", err)
+		}
+	})
+}
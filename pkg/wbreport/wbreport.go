@@ -0,0 +1,95 @@
+// Package wbreport reads the Wildberries weekly realization report, either
+// the JSON returned by the reportDetailByPeriod method of the statistics API
+// or the XLSX downloaded from the seller portal.
+package wbreport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrInvalidReport = errors.New("invalid realization report")
+
+// Row is one line of the realization report. Money fields are in rubles as
+// reported; for returns WB reports them as positive numbers too.
+type Row struct {
+	// Line is the position of the row: the element number in JSON or the
+	// sheet row in XLSX.
+	Line int `json:"-"`
+	// RRDID is the report row id, unique across reports. The XLSX from the
+	// seller portal does not have it.
+	RRDID int64 `json:"rrd_id"`
+	// Number is the "№" column of the XLSX: the row number within one
+	// report, so it identifies a row only together with the report id.
+	Number       int64   `json:"-"`
+	ReportID     int64   `json:"realizationreport_id"`
+	DocType      string  `json:"doc_type_name"`
+	OperName     string  `json:"supplier_oper_name"`
+	OperDate     string  `json:"rr_dt"`
+	SaleDate     string  `json:"sale_dt"`
+	RetailAmount float64 `json:"retail_amount"`
+	ForPay       float64 `json:"ppvz_for_pay"`
+	AcquiringFee float64 `json:"acquiring_fee"`
+	Delivery     float64 `json:"delivery_rub"`
+	Penalty      float64 `json:"penalty"`
+	StorageFee   float64 `json:"storage_fee"`
+}
+
+// IsReturn reports whether the row is a customer return.
+func (r Row) IsReturn() bool {
+	return strings.EqualFold(strings.TrimSpace(r.DocType), "Возврат")
+}
+
+// Date returns the operation date, or the sale date for rows without one.
+// Dates without a zone are read in loc.
+func (r Row) Date(loc *time.Location) (time.Time, error) {
+	value := strings.TrimSpace(r.OperDate)
+	if value == "" {
+		value = strings.TrimSpace(r.SaleDate)
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04:05", time.DateOnly, "02.01.2006"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date '%s'", value)
+}
+
+// ParseJSON reads the array returned by reportDetailByPeriod.
+func ParseJSON(r io.Reader) ([]Row, error) {
+	decoder := json.NewDecoder(r)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidReport, err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("%w: expected a JSON array", ErrInvalidReport)
+	}
+
+	var rows []Row
+	for decoder.More() {
+		var row Row
+		if err = decoder.Decode(&row); err != nil {
+			return nil, fmt.Errorf("%w: element %d: %w", ErrInvalidReport, len(rows)+1, err)
+		}
+		row.Line = len(rows) + 1
+		rows = append(rows, row)
+	}
+
+	if _, err = decoder.Token(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidReport, err)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,319 @@
+package wbreport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsxColumns maps the headers of the downloaded report, and the API field
+// names for files assembled by hand, to Row fields.
+var xlsxColumns = map[string]string{
+	"№":             "row_number",
+	"номер отчета":  "realizationreport_id",
+	"тип документа": "doc_type_name",
+	"обоснование для оплаты":                         "supplier_oper_name",
+	"дата операции":                                  "rr_dt",
+	"дата продажи":                                   "sale_dt",
+	"вайлдберриз реализовал товар (пр)":              "retail_amount",
+	"к перечислению продавцу за реализованный товар": "ppvz_for_pay",
+	"эквайринг/комиссии за организацию платежей":     "acquiring_fee",
+	"услуги по доставке товара покупателю":           "delivery_rub",
+	"общая сумма штрафов":                            "penalty",
+	"хранение":                                       "storage_fee",
+}
+
+// rowFields are the JSON names of the Row fields.
+var rowFields = []string{
+	"rrd_id", "realizationreport_id", "doc_type_name", "supplier_oper_name", "rr_dt", "sale_dt",
+	"retail_amount", "ppvz_for_pay", "acquiring_fee", "delivery_rub", "penalty", "storage_fee",
+}
+
+var xlsxDateFields = []string{"rr_dt", "sale_dt"}
+
+// excelEpoch is day zero of the 1900 date system used by serial dates.
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+type xlsxRow struct {
+	Number int `xml:"r,attr"`
+	Cells  []struct {
+		Ref    string   `xml:"r,attr"`
+		Type   string   `xml:"t,attr"`
+		Value  string   `xml:"v"`
+		Inline xlsxText `xml:"is"`
+	} `xml:"c"`
+}
+
+// xlsxText is a string item: plain text or rich text runs.
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+
+	return b.String()
+}
+
+// ParseXLSX reads the first sheet of the report downloaded from the seller
+// portal. The header row is the first row that names the payment basis
+// column.
+func ParseXLSX(r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidReport, err)
+	}
+
+	sharedStrings, err := readSharedStrings(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet, err := firstSheet(zr)
+	if err != nil {
+		return nil, err
+	}
+	defer sheet.Close()
+
+	decoder := xml.NewDecoder(sheet)
+
+	var (
+		rows    []Row
+		columns map[string]int
+	)
+
+	for {
+		token, tokenErr := decoder.Token()
+		if errors.Is(tokenErr, io.EOF) {
+			break
+		}
+		if tokenErr != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidReport, tokenErr)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err = decoder.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidReport, err)
+		}
+
+		values := make(map[int]string, len(row.Cells))
+		for i, cell := range row.Cells {
+			col := i
+			if cell.Ref != "" {
+				col = columnIndex(cell.Ref)
+			}
+
+			switch cell.Type {
+			case "s":
+				idx, convErr := strconv.Atoi(cell.Value)
+				if convErr != nil || idx < 0 || idx >= len(sharedStrings) {
+					return nil, fmt.Errorf("%w: row %d: invalid shared string", ErrInvalidReport, row.Number)
+				}
+				values[col] = sharedStrings[idx]
+			case "inlineStr":
+				values[col] = cell.Inline.String()
+			default:
+				values[col] = cell.Value
+			}
+		}
+
+		if columns == nil {
+			columns = headerColumns(values)
+			continue
+		}
+
+		reportRow, rowErr := rowFromValues(values, columns)
+		if rowErr != nil {
+			return nil, fmt.Errorf("%w: row %d: %w", ErrInvalidReport, row.Number, rowErr)
+		}
+		if reportRow == nil {
+			continue
+		}
+		reportRow.Line = row.Number
+		rows = append(rows, *reportRow)
+	}
+
+	if columns == nil {
+		return nil, fmt.Errorf("%w: no header row with 'Обоснование для оплаты'", ErrInvalidReport)
+	}
+
+	return rows, nil
+}
+
+// headerColumns returns the Row fields by column index, or nil if values is
+// not the header row.
+func headerColumns(values map[int]string) map[string]int {
+	columns := make(map[string]int)
+	for col, value := range values {
+		name := strings.ToLower(strings.Join(strings.Fields(value), " "))
+		if field, ok := xlsxColumns[name]; ok {
+			columns[field] = col
+		} else if slices.Contains(rowFields, name) {
+			columns[name] = col
+		}
+	}
+
+	if _, ok := columns["supplier_oper_name"]; !ok {
+		return nil
+	}
+
+	return columns
+}
+
+// rowFromValues builds a Row from a data row. Empty rows, such as a totals
+// footer without a payment basis, return nil.
+func rowFromValues(values map[int]string, columns map[string]int) (*Row, error) {
+	field := func(name string) string {
+		col, ok := columns[name]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(values[col])
+	}
+
+	if field("supplier_oper_name") == "" {
+		return nil, nil
+	}
+
+	row := Row{
+		DocType:  field("doc_type_name"),
+		OperName: field("supplier_oper_name"),
+	}
+
+	var err error
+	for name, dst := range map[string]*int64{
+		"rrd_id":               &row.RRDID,
+		"row_number":           &row.Number,
+		"realizationreport_id": &row.ReportID,
+	} {
+		if value := field(name); value != "" {
+			if *dst, err = strconv.ParseInt(strings.TrimSuffix(value, ".0"), 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s '%s'", name, value)
+			}
+		}
+	}
+
+	for name, dst := range map[string]*float64{
+		"retail_amount": &row.RetailAmount,
+		"ppvz_for_pay":  &row.ForPay,
+		"acquiring_fee": &row.AcquiringFee,
+		"delivery_rub":  &row.Delivery,
+		"penalty":       &row.Penalty,
+		"storage_fee":   &row.StorageFee,
+	} {
+		if value := field(name); value != "" {
+			if *dst, err = strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64); err != nil {
+				return nil, fmt.Errorf("invalid %s '%s'", name, value)
+			}
+		}
+	}
+
+	for _, name := range xlsxDateFields {
+		value := field(name)
+		// Date cells hold serial day numbers.
+		if serial, convErr := strconv.ParseFloat(value, 64); convErr == nil {
+			value = excelEpoch.AddDate(0, 0, int(serial)).Format(time.DateOnly)
+		}
+
+		if name == "rr_dt" {
+			row.OperDate = value
+		} else {
+			row.SaleDate = value
+		}
+	}
+
+	return &row, nil
+}
+
+func readSharedStrings(zr *zip.Reader) ([]string, error) {
+	f, err := zr.Open("xl/sharedStrings.xml")
+	if err != nil {
+		// Workbooks with inline strings only have no shared strings part.
+		return nil, nil
+	}
+	defer f.Close()
+
+	var sst struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if err = xml.NewDecoder(f).Decode(&sst); err != nil {
+		return nil, fmt.Errorf("%w: sharedStrings: %w", ErrInvalidReport, err)
+	}
+
+	strs := make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		strs[i] = item.String()
+	}
+
+	return strs, nil
+}
+
+// firstSheet opens sheet1, or the first worksheet by name when the
+// workbook numbers its sheets differently.
+func firstSheet(zr *zip.Reader) (io.ReadCloser, error) {
+	var names []string
+	for _, f := range zr.File {
+		if path.Dir(f.Name) == "xl/worksheets" && strings.HasSuffix(f.Name, ".xml") {
+			names = append(names, f.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: workbook has no worksheets", ErrInvalidReport)
+	}
+
+	name := "xl/worksheets/sheet1.xml"
+	if !slices.Contains(names, name) {
+		slices.Sort(names)
+		name = names[0]
+	}
+
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidReport, err)
+	}
+
+	return f, nil
+}
+
+// columnIndex converts the letters of a cell reference such as "AB12" to a
+// zero-based column index.
+func columnIndex(ref string) int {
+	col := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+	}
+
+	return col - 1
+}